@@ -0,0 +1,126 @@
+package banner
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const (
+	AuditCreate     = "create"
+	AuditUpdate     = "update"
+	AuditDelete     = "delete"
+	AuditActivate   = "activate"
+	AuditBulkDelete = "bulk_delete"
+	AuditRestore    = "restore"
+	AuditPurge      = "purge"
+)
+
+// Actor is the user behind a mutation and the request it was made in.
+type Actor struct {
+	UserId    int
+	RequestId string
+}
+
+// Change holds a banner field before and after a mutation.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditEntry struct {
+	Id        int       `json:"id" db:"id"`
+	ActorId   int       `json:"actor_id" db:"actor_id"`
+	Action    string    `json:"action" db:"action"`
+	BannerId  int       `json:"banner_id" db:"banner_id"`
+	Diff      Diff      `json:"diff" db:"diff"`
+	RequestId string    `json:"request_id" db:"request_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type AuditFilter struct {
+	ActorId  *int
+	BannerId *int
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+// auditIgnoredFields change on every write or are computed on read, so they
+// are left out of audit diffs.
+var auditIgnoredFields = map[string]bool{
+	"banner_id":       true,
+	"created_at":      true,
+	"updated_at":      true,
+	"state":           true,
+	"missing_locales": true,
+	"row_version":     true,
+}
+
+// NewAuditEntry describes the mutation of a banner by the actor. A nil state
+// stands for a banner that doesn't exist yet or anymore.
+func NewAuditEntry(actor Actor, action string, bannerId int, before, after *Banner) (AuditEntry, error) {
+	diff, err := bannerDiff(before, after)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+
+	return AuditEntry{
+		ActorId:   actor.UserId,
+		Action:    action,
+		BannerId:  bannerId,
+		Diff:      diff,
+		RequestId: actor.RequestId,
+	}, nil
+}
+
+// bannerDiff lists the fields that differ between two banner states.
+func bannerDiff(before, after *Banner) (Diff, error) {
+	beforeFields, err := bannerFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := bannerFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(Diff)
+	for name, value := range afterFields {
+		if old, ok := beforeFields[name]; !ok || !reflect.DeepEqual(old, value) {
+			diff[name] = Change{Before: beforeFields[name], After: value}
+		}
+	}
+	for name, value := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			diff[name] = Change{Before: value}
+		}
+	}
+
+	return diff, nil
+}
+
+func bannerFields(b *Banner) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if b == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range fields {
+		if auditIgnoredFields[name] || value == nil {
+			delete(fields, name)
+		}
+	}
+
+	return fields, nil
+}
@@ -1,29 +1,170 @@
 package banner
 
-type Content struct {
-	Title string `json:"title" db:"title" binding:"required"`
-	Text  string `json:"text" db:"text" binding:"required"`
-	Url   string `json:"url" db:"url" binding:"required"`
+import "time"
+
+const (
+	StateScheduled = "scheduled"
+	StateLive      = "live"
+	StateExpired   = "expired"
+)
+
+type Variant struct {
+	Id      int     `json:"id" db:"id"`
+	Content Content `json:"content" binding:"required"`
+	Weight  int     `json:"weight" db:"weight" binding:"required,min=1"`
+}
+
+type VariantReport struct {
+	VariantId int `json:"variant_id" db:"variant_id"`
+	Weight    int `json:"weight" db:"weight"`
+	Users     int `json:"users" db:"users"`
 }
 
 type Banner struct {
+	Id        int     `json:"banner_id" db:"id"`
 	TagIds    []int   `json:"tag_ids" db:"tag_ids" binding:"required"`
 	FeatureId int     `json:"feature_id" db:"feature_id" binding:"required"`
 	Content   Content `json:"content" binding:"required"`
-	IsActive  bool    `json:"is_active" db:"is_active"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	// LocalizedContent overrides Content for the listed locales.
+	LocalizedContent LocalizedContent `json:"localized_content,omitempty" db:"localized_content"`
+	// Targeting limits the banner to users whose attributes match the rule.
+	Targeting *Rule      `json:"targeting,omitempty" db:"targeting"`
+	IsActive  bool       `json:"is_active" db:"is_active"`
+	StartsAt  *time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at" db:"ends_at"`
+	// MaxImpressionsPerUser caps how often one user sees the banner, per
+	// ImpressionPeriod seconds or for the banner lifetime when it is unset.
+	MaxImpressionsPerUser *int      `json:"max_impressions_per_user" db:"max_impressions_per_user"`
+	ImpressionPeriod      *int      `json:"impression_period" db:"impression_period"`
+	RolloutPercent        int       `json:"rollout_percent" db:"rollout_percent"`
+	Priority              int       `json:"priority" db:"priority"`
+	Variants              []Variant `json:"variants,omitempty"`
+	// Status is the workflow status and State the schedule state. RowVersion
+	// grows on every change and is served as the banner's ETag.
+	Status         string   `json:"status" db:"status"`
+	RowVersion     int      `json:"row_version" db:"row_version"`
+	State          string   `json:"state,omitempty"`
+	MissingLocales []string `json:"missing_locales,omitempty"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
 }
 
-type UserBannerInput struct {
-	TagId           int  `json:"tag_id"`
-	FeatureId       int  `json:"feature_id"`
-	UseLastRevision bool `json:"use_last_revision"`
+// ScheduleState reports where now falls relative to the banner's activation
+// window. Banners without a window are always live.
+func (b Banner) ScheduleState(now time.Time) string {
+	if b.StartsAt != nil && now.Before(*b.StartsAt) {
+		return StateScheduled
+	}
+	if b.EndsAt != nil && !now.Before(*b.EndsAt) {
+		return StateExpired
+	}
+	return StateLive
 }
 
-type FilterInput struct {
+type Revision struct {
+	BannerId              int              `json:"banner_id" db:"banner_id"`
+	Version               int              `json:"version" db:"version"`
+	TagIds                []int            `json:"tag_ids" db:"tag_ids"`
+	FeatureId             int              `json:"feature_id" db:"feature_id"`
+	Content               Content          `json:"content"`
+	LocalizedContent      LocalizedContent `json:"localized_content,omitempty" db:"localized_content"`
+	Targeting             *Rule            `json:"targeting,omitempty" db:"targeting"`
+	IsActive              bool             `json:"is_active" db:"is_active"`
+	StartsAt              *time.Time       `json:"starts_at" db:"starts_at"`
+	EndsAt                *time.Time       `json:"ends_at" db:"ends_at"`
+	MaxImpressionsPerUser *int             `json:"max_impressions_per_user" db:"max_impressions_per_user"`
+	ImpressionPeriod      *int             `json:"impression_period" db:"impression_period"`
+	RolloutPercent        int              `json:"rollout_percent" db:"rollout_percent"`
+	Priority              int              `json:"priority" db:"priority"`
+	AuthorId              int              `json:"author_id" db:"author_id"`
+	CreatedAt             string           `json:"created_at" db:"created_at"`
+	Current               bool             `json:"current"`
+}
+
+type UserBanner struct {
+	BannerId  int     `json:"banner_id"`
+	VariantId int     `json:"variant_id,omitempty"`
+	Locale    string  `json:"locale,omitempty"`
+	Content   Content `json:"content"`
+}
+
+type UserBannerInput struct {
 	TagId     int `json:"tag_id"`
 	FeatureId int `json:"feature_id"`
-	Limit     int `json:"limit"`
-	Offset    int `json:"offset"`
+	// UseLastRevision reads the banner from the database, skipping the cache.
+	UseLastRevision bool           `json:"use_last_revision"`
+	Attributes      UserAttributes `json:"attributes"`
+	// Vars are substituted into the {{name}} placeholders of the content.
+	Vars map[string]string `json:"vars" binding:"omitempty,max=50,dive,keys,max=64,endkeys,max=256"`
+	// Locales lists the locales the user accepts, most preferred first.
+	Locales  []string `json:"-"`
+	Nickname string   `json:"-"`
+}
+
+// FilterInput selects banners matching all the set filters. Created and
+// updated ranges include From and exclude To.
+type FilterInput struct {
+	TagId       int        `json:"tag_id" form:"tag_id"`
+	FeatureId   int        `json:"feature_id" form:"feature_id"`
+	IsActive    *bool      `json:"is_active" form:"is_active"`
+	State       string     `json:"state" form:"state"`
+	Status      string     `json:"status" form:"status"`
+	CreatedFrom *time.Time `json:"created_from" form:"created_from"`
+	CreatedTo   *time.Time `json:"created_to" form:"created_to"`
+	UpdatedFrom *time.Time `json:"updated_from" form:"updated_from"`
+	UpdatedTo   *time.Time `json:"updated_to" form:"updated_to"`
+	// Search matches banners with the text in one of their content values.
+	Search string `json:"search" form:"search"`
+	// Sort is one of the Sort* fields, descending when prefixed with "-".
+	Sort   string `json:"sort" form:"sort"`
+	Cursor string `json:"cursor" form:"cursor"`
+	Limit  int    `json:"limit" form:"limit"`
+	// Offset was replaced by Cursor and is only kept to reject it.
+	Offset *int `json:"offset" form:"offset"`
+}
+
+const (
+	SortById        = "id"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByPriority  = "priority"
+)
+
+// BannerCursor is the position after the last banner of a page: its id and
+// the value of the field the banners are sorted by.
+type BannerCursor struct {
+	Sort  string `json:"sort"`
+	Value string `json:"value,omitempty"`
+	Id    int    `json:"id"`
+}
+
+type BannerPage struct {
+	Banners    []Banner
+	NextCursor string
+	Total      int
+}
+
+type BulkDeleteInput struct {
+	TagId     *int `json:"tag_id"`
+	FeatureId *int `json:"feature_id"`
+}
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+type Job struct {
+	Id        int    `json:"id" db:"id"`
+	Type      string `json:"type" db:"type"`
+	Status    string `json:"status" db:"status"`
+	TagId     *int   `json:"tag_id,omitempty" db:"tag_id"`
+	FeatureId *int   `json:"feature_id,omitempty" db:"feature_id"`
+	Total     int    `json:"total" db:"total"`
+	Processed int    `json:"processed" db:"processed"`
+	Error     string `json:"error,omitempty" db:"error"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+	UpdatedAt string `json:"updated_at" db:"updated_at"`
 }
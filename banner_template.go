@@ -0,0 +1,57 @@
+package banner
+
+import "time"
+
+// BannerTemplate is saved banner content new banners can be created from.
+type BannerTemplate struct {
+	Id                    int              `json:"id" db:"id"`
+	Name                  string           `json:"name" db:"name" binding:"required"`
+	Description           string           `json:"description" db:"description"`
+	FeatureId             int              `json:"feature_id" db:"feature_id" binding:"required"`
+	Content               Content          `json:"content" db:"content" binding:"required"`
+	LocalizedContent      LocalizedContent `json:"localized_content,omitempty" db:"localized_content"`
+	Targeting             *Rule            `json:"targeting,omitempty" db:"targeting"`
+	IsActive              bool             `json:"is_active" db:"is_active"`
+	MaxImpressionsPerUser *int             `json:"max_impressions_per_user" db:"max_impressions_per_user" binding:"omitempty,min=1"`
+	ImpressionPeriod      *int             `json:"impression_period" db:"impression_period" binding:"omitempty,min=1"`
+	RolloutPercent        *int             `json:"rollout_percent" db:"rollout_percent" binding:"omitempty,min=0,max=100"`
+	Priority              int              `json:"priority" db:"priority"`
+	CreatedAt             string           `json:"created_at" db:"created_at"`
+	UpdatedAt             string           `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateBannerTemplateInput struct {
+	Name                  *string          `json:"name"`
+	Description           *string          `json:"description"`
+	FeatureId             *int             `json:"feature_id"`
+	Content               Content          `json:"content"`
+	LocalizedContent      LocalizedContent `json:"localized_content"`
+	Targeting             *Rule            `json:"targeting"`
+	IsActive              *bool            `json:"is_active"`
+	MaxImpressionsPerUser *int             `json:"max_impressions_per_user" binding:"omitempty,min=1"`
+	ImpressionPeriod      *int             `json:"impression_period" binding:"omitempty,min=1"`
+	RolloutPercent        *int             `json:"rollout_percent" binding:"omitempty,min=0,max=100"`
+	Priority              *int             `json:"priority"`
+}
+
+// InstantiateTemplateInput places a banner created from a template.
+type InstantiateTemplateInput struct {
+	TagIds    []int      `json:"tag_ids" binding:"required"`
+	FeatureId *int       `json:"feature_id"`
+	Priority  *int       `json:"priority"`
+	IsActive  *bool      `json:"is_active"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	// Status and CreatedAt are set by the handler.
+	Status    string `json:"-"`
+	CreatedAt string `json:"-"`
+}
+
+// CloneBannerInput overrides the placement of a cloned banner.
+type CloneBannerInput struct {
+	TagIds    []int  `json:"tag_ids"`
+	FeatureId *int   `json:"feature_id"`
+	Priority  *int   `json:"priority"`
+	Status    string `json:"-"`
+	CreatedAt string `json:"-"`
+}
@@ -6,14 +6,18 @@ import (
 	"banner/pkg/repository"
 	"banner/pkg/service"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -45,13 +49,33 @@ func main() {
 	}
 	logrus.Debug("Migrations applied successfully")
 
+	cache, err := newCache()
+	if err != nil {
+		logrus.Fatalf("failed to initialize cache: %s", err.Error())
+	}
+
+	retention, err := time.ParseDuration(getEnv("DELETED_BANNER_RETENTION", "720h"))
+	if err != nil {
+		logrus.Fatalf("invalid DELETED_BANNER_RETENTION: %s", err.Error())
+	}
+
+	idempotencyTTL, err := time.ParseDuration(getEnv("IDEMPOTENCY_KEY_TTL", "24h"))
+	if err != nil {
+		logrus.Fatalf("invalid IDEMPOTENCY_KEY_TTL: %s", err.Error())
+	}
+
 	repos := repository.NewRepository(db)
-	services := service.NewService(repos)
+	services := service.NewService(repos, cache, service.Config{
+		FallbackLocales:  service.ParseLocales(getEnv("LOCALE_FALLBACK", "en")),
+		SupportedLocales: service.ParseLocales(getEnv("SUPPORTED_LOCALES", "en")),
+		DeletedRetention: retention,
+		IdempotencyTTL:   idempotencyTTL,
+	})
 	handlers := handler.NewHandler(services)
 
 	srv := new(banner.Server)
 	go func() {
-		if err := srv.Run("8000", handlers.InitRoutes()); err != nil {
+		if err := srv.Run("8000", handlers.InitRoutes()); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logrus.Fatalf("Error occured while running http server: %s", err.Error())
 		}
 	}()
@@ -67,7 +91,41 @@ func main() {
 		logrus.Errorf("error occured on server shutting down: %s", err.Error())
 	}
 
+	services.Close()
+
 	if err := db.Close(); err != nil {
 		logrus.Errorf("error occured on db connection close: %s", err.Error())
 	}
 }
+
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func newCache() (service.Cache, error) {
+	ttl, err := time.ParseDuration(getEnv("CACHE_TTL", "5m"))
+	if err != nil {
+		return nil, err
+	}
+
+	switch cacheType := getEnv("CACHE_TYPE", "memory"); cacheType {
+	case "none":
+		return nil, nil
+	case "memory":
+		return service.NewMemoryCache(ttl), nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     getEnv("REDIS_ADDR", "redis:6379"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+		})
+		if err = client.Ping(context.Background()).Err(); err != nil {
+			return nil, err
+		}
+		return service.NewRedisCache(client, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", cacheType)
+	}
+}
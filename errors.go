@@ -0,0 +1,45 @@
+package banner
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInUse           = errors.New("entity is used by banners")
+	ErrNameTaken       = errors.New("name is already taken")
+	ErrInvalidSchedule = errors.New("ends_at must be after starts_at")
+	ErrInvalidState    = errors.New("state must be one of scheduled, live, expired")
+	ErrInvalidStatus   = errors.New("status must be one of draft, in_review, approved, published, archived")
+	ErrInvalidSort     = errors.New("sort must be one of id, created_at, updated_at, priority, optionally prefixed with -")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidLimit    = errors.New("limit must be between 1 and 1000")
+	ErrInvalidOffset   = errors.New("offset is no longer supported, use cursor from next_cursor instead")
+	ErrInvalidAction   = errors.New("banner status does not allow this action")
+	ErrVersionMismatch = errors.New("banner was changed since it was read, reload it and retry")
+	ErrKeyReused       = errors.New("idempotency key was already used with a different request")
+	ErrKeyInProgress   = errors.New("a request with this idempotency key is still in progress")
+	ErrInvalidCap      = errors.New("impression_period requires max_impressions_per_user")
+	ErrInvalidSchema   = errors.New("invalid content schema")
+	ErrInvalidContent  = errors.New("content does not match feature schema")
+	ErrInvalidTemplate = errors.New("invalid content template")
+	ErrInvalidRule     = errors.New("invalid targeting rule")
+	ErrInvalidLocale   = errors.New("localized_content must map locale tags such as en or pt-br to content")
+)
+
+type UnknownIdsError struct {
+	Entity string
+	Ids    []int
+}
+
+func (e *UnknownIdsError) Error() string {
+	return fmt.Sprintf("unknown %s ids: %v", e.Entity, e.Ids)
+}
+
+type ConflictError struct {
+	BannerIds []int
+}
+
+func (e *ConflictError) Error() string {
+	return "banner with specified tag_ids, feature_id and priority already exists"
+}
@@ -0,0 +1,28 @@
+package banner
+
+import "time"
+
+const (
+	EventImpression = "impression"
+	EventClick      = "click"
+)
+
+type Event struct {
+	BannerId  int
+	VariantId int
+	UserId    int
+	Type      string
+	CreatedAt time.Time
+}
+
+type ClickInput struct {
+	VariantId int `json:"variant_id"`
+}
+
+type DailyStats struct {
+	Day         string  `json:"day" db:"day"`
+	Impressions int     `json:"impressions" db:"impressions"`
+	UniqueUsers int     `json:"unique_users" db:"unique_users"`
+	Clicks      int     `json:"clicks" db:"clicks"`
+	CTR         float64 `json:"ctr" db:"-"`
+}
@@ -0,0 +1,17 @@
+package banner
+
+type Feature struct {
+	Id            int    `json:"id" db:"id"`
+	Name          string `json:"name" db:"name" binding:"required"`
+	Description   string `json:"description" db:"description"`
+	Owner         string `json:"owner" db:"owner"`
+	ContentSchema Schema `json:"content_schema,omitempty" db:"content_schema"`
+	CreatedAt     string `json:"created_at" db:"created_at"`
+}
+
+type UpdateFeatureInput struct {
+	Name          *string `json:"name"`
+	Description   *string `json:"description"`
+	Owner         *string `json:"owner"`
+	ContentSchema Schema  `json:"content_schema"`
+}
@@ -0,0 +1,15 @@
+package banner
+
+import "net/http"
+
+// IdempotencyRecord is a mutating request made with an Idempotency-Key and,
+// once it has completed, the response to replay for retries. A record
+// without a status code is still in progress.
+type IdempotencyRecord struct {
+	UserId      int         `db:"user_id"`
+	Key         string      `db:"key"`
+	Fingerprint string      `db:"fingerprint"`
+	StatusCode  int         `db:"status_code"`
+	Header      http.Header `db:"-"`
+	Body        []byte      `db:"body"`
+}
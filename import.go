@@ -0,0 +1,24 @@
+package banner
+
+// ImportRow is a banner read from line Line of an import file. Error is set
+// when the line could not be parsed into a banner.
+type ImportRow struct {
+	Line   int
+	Banner Banner
+	Error  string
+}
+
+type ImportRowError struct {
+	Line      int    `json:"line"`
+	Error     string `json:"error"`
+	BannerIds []int  `json:"banner_ids,omitempty"`
+}
+
+// ImportResult reports the banners an import created, or why it was rejected.
+// Nothing is created when any row has an error or the import is a dry run.
+type ImportResult struct {
+	Total     int              `json:"total"`
+	DryRun    bool             `json:"dry_run"`
+	BannerIds []int            `json:"banner_ids,omitempty"`
+	Errors    []ImportRowError `json:"errors,omitempty"`
+}
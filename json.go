@@ -0,0 +1,78 @@
+package banner
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Content is an arbitrary JSON object shown to users, stored as JSONB.
+type Content map[string]interface{}
+
+func (c Content) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *Content) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// LocalizedContent maps a locale such as "en" or "pt-br" to the content shown
+// to users of that locale, stored as JSONB.
+type LocalizedContent map[string]Content
+
+func (l LocalizedContent) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *LocalizedContent) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
+
+// Diff maps changed banner fields to their values before and after a
+// mutation, stored as JSONB.
+type Diff map[string]Change
+
+func (d Diff) Value() (driver.Value, error) {
+	if d == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *Diff) Scan(src interface{}) error {
+	return scanJSON(src, d)
+}
+
+// Schema is a JSON Schema document, stored as JSONB.
+type Schema map[string]interface{}
+
+func (s Schema) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *Schema) Scan(src interface{}) error {
+	return scanJSON(src, s)
+}
+
+func scanJSON(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", src)
+	}
+}
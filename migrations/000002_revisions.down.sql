@@ -0,0 +1,3 @@
+DROP TABLE banner_revisions;
+
+ALTER TABLE banners DROP COLUMN version;
@@ -0,0 +1,20 @@
+ALTER TABLE banners ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE banner_revisions
+(
+    id            SERIAL       PRIMARY KEY,
+    banner_id     INTEGER      NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    version       INTEGER      NOT NULL,
+    tag_ids       INTEGER[]    NOT NULL,
+    feature_id    INTEGER      NOT NULL,
+    title         VARCHAR(255) NOT NULL,
+    text          TEXT         NOT NULL,
+    url           varchar(255) NOT NULL,
+    is_active     BOOLEAN      NOT NULL,
+    author_id     INTEGER,
+    created_at    TIMESTAMP    NOT NULL,
+    UNIQUE (banner_id, version)
+);
+
+INSERT INTO banner_revisions (banner_id, version, tag_ids, feature_id, title, text, url, is_active, created_at)
+SELECT id, version, tag_ids, feature_id, title, text, url, is_active, updated_at FROM banners;
@@ -0,0 +1,13 @@
+CREATE TABLE jobs
+(
+    id            SERIAL       PRIMARY KEY,
+    type          VARCHAR(31)  NOT NULL,
+    status        VARCHAR(15)  NOT NULL,
+    tag_id        INTEGER,
+    feature_id    INTEGER,
+    total         INTEGER      NOT NULL DEFAULT 0,
+    processed     INTEGER      NOT NULL DEFAULT 0,
+    error         TEXT         NOT NULL DEFAULT '',
+    created_at    TIMESTAMP    NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMP    NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+ALTER TABLE banners DROP CONSTRAINT banners_feature_id_fkey;
+
+DROP TABLE tags;
+
+DROP TABLE features;
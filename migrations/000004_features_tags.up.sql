@@ -0,0 +1,28 @@
+CREATE TABLE features
+(
+    id            SERIAL       PRIMARY KEY,
+    name          VARCHAR(255) NOT NULL,
+    description   TEXT         NOT NULL DEFAULT '',
+    owner         VARCHAR(255) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP    NOT NULL
+);
+
+CREATE TABLE tags
+(
+    id            SERIAL       PRIMARY KEY,
+    name          VARCHAR(255) NOT NULL,
+    description   TEXT         NOT NULL DEFAULT '',
+    owner         VARCHAR(255) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP    NOT NULL
+);
+
+INSERT INTO features (id, name, created_at)
+SELECT DISTINCT feature_id, 'feature ' || feature_id, now() FROM banners;
+
+INSERT INTO tags (id, name, created_at)
+SELECT DISTINCT tag_id, 'tag ' || tag_id, now() FROM banners, unnest(tag_ids) AS tag_id;
+
+SELECT setval(pg_get_serial_sequence('features', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM features;
+SELECT setval(pg_get_serial_sequence('tags', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM tags;
+
+ALTER TABLE banners ADD CONSTRAINT banners_feature_id_fkey FOREIGN KEY (feature_id) REFERENCES features (id);
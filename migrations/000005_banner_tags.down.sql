@@ -0,0 +1,9 @@
+ALTER TABLE banners ADD COLUMN tag_ids INTEGER[] NOT NULL DEFAULT '{}';
+
+UPDATE banners b SET tag_ids = ARRAY(SELECT tag_id FROM banner_tags WHERE banner_id = b.id ORDER BY tag_id);
+
+ALTER TABLE banners ALTER COLUMN tag_ids DROP DEFAULT;
+
+DROP TABLE banner_tags;
+
+ALTER TABLE banners DROP CONSTRAINT banners_id_feature_id_key;
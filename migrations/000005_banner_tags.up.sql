@@ -0,0 +1,38 @@
+ALTER TABLE banners ADD CONSTRAINT banners_id_feature_id_key UNIQUE (id, feature_id);
+
+CREATE TABLE banner_tags
+(
+    banner_id     INTEGER      NOT NULL,
+    tag_id        INTEGER      NOT NULL REFERENCES tags (id),
+    feature_id    INTEGER      NOT NULL,
+    PRIMARY KEY (banner_id, tag_id),
+    FOREIGN KEY (banner_id, feature_id) REFERENCES banners (id, feature_id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE UNIQUE INDEX banner_tags_tag_id_feature_id_key ON banner_tags (tag_id, feature_id);
+
+-- Banners that already share a (tag, feature) pair have to be fixed by hand:
+-- the migration stops and lists them instead of picking one.
+DO
+$$
+    DECLARE
+        conflicts TEXT;
+    BEGIN
+        SELECT string_agg(format('tag %s, feature %s: banners %s', tag_id, feature_id, banner_ids), '; ')
+        INTO conflicts
+        FROM (SELECT t.tag_id, b.feature_id, array_agg(DISTINCT b.id ORDER BY b.id) AS banner_ids
+              FROM banners b, unnest(b.tag_ids) AS t(tag_id)
+              GROUP BY t.tag_id, b.feature_id
+              HAVING count(DISTINCT b.id) > 1) c;
+
+        IF conflicts IS NOT NULL THEN
+            RAISE EXCEPTION 'banners share tag and feature pairs: %', conflicts;
+        END IF;
+    END
+$$;
+
+INSERT INTO banner_tags (banner_id, tag_id, feature_id)
+SELECT DISTINCT b.id, t.tag_id, b.feature_id
+FROM banners b, unnest(b.tag_ids) AS t(tag_id);
+
+ALTER TABLE banners DROP COLUMN tag_ids;
@@ -0,0 +1,5 @@
+ALTER TABLE banner_revisions DROP COLUMN ends_at;
+ALTER TABLE banner_revisions DROP COLUMN starts_at;
+
+ALTER TABLE banners DROP COLUMN ends_at;
+ALTER TABLE banners DROP COLUMN starts_at;
@@ -0,0 +1,5 @@
+ALTER TABLE banners ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE banners ADD COLUMN ends_at TIMESTAMPTZ;
+
+ALTER TABLE banner_revisions ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE banner_revisions ADD COLUMN ends_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+DROP TABLE variant_assignments;
+
+DROP TABLE banner_variants;
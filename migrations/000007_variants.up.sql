@@ -0,0 +1,19 @@
+CREATE TABLE banner_variants
+(
+    id            SERIAL       PRIMARY KEY,
+    banner_id     INTEGER      NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    title         VARCHAR(255) NOT NULL,
+    text          TEXT         NOT NULL,
+    url           varchar(255) NOT NULL,
+    weight        INTEGER      NOT NULL CHECK (weight > 0)
+);
+
+CREATE INDEX banner_variants_banner_id_idx ON banner_variants (banner_id);
+
+CREATE TABLE variant_assignments
+(
+    variant_id    INTEGER      NOT NULL REFERENCES banner_variants (id) ON DELETE CASCADE,
+    user_id       INTEGER      NOT NULL,
+    assigned_at   TIMESTAMP    NOT NULL DEFAULT now(),
+    PRIMARY KEY (variant_id, user_id)
+);
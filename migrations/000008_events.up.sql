@@ -0,0 +1,11 @@
+CREATE TABLE events
+(
+    id            BIGSERIAL    PRIMARY KEY,
+    banner_id     INTEGER      NOT NULL,
+    variant_id    INTEGER,
+    user_id       INTEGER      NOT NULL,
+    type          VARCHAR(15)  NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX events_banner_id_created_at_idx ON events (banner_id, created_at);
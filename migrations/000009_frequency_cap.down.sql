@@ -0,0 +1,7 @@
+DROP TABLE impression_counts;
+
+ALTER TABLE banner_revisions DROP COLUMN impression_period;
+ALTER TABLE banner_revisions DROP COLUMN max_impressions_per_user;
+
+ALTER TABLE banners DROP COLUMN impression_period;
+ALTER TABLE banners DROP COLUMN max_impressions_per_user;
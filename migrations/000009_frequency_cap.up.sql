@@ -0,0 +1,14 @@
+ALTER TABLE banners ADD COLUMN max_impressions_per_user INTEGER CHECK (max_impressions_per_user > 0);
+ALTER TABLE banners ADD COLUMN impression_period INTEGER CHECK (impression_period > 0);
+
+ALTER TABLE banner_revisions ADD COLUMN max_impressions_per_user INTEGER;
+ALTER TABLE banner_revisions ADD COLUMN impression_period INTEGER;
+
+CREATE TABLE impression_counts
+(
+    banner_id     INTEGER      NOT NULL REFERENCES banners (id) ON DELETE CASCADE,
+    user_id       INTEGER      NOT NULL,
+    window_start  TIMESTAMPTZ  NOT NULL,
+    count         INTEGER      NOT NULL DEFAULT 0,
+    PRIMARY KEY (banner_id, user_id, window_start)
+);
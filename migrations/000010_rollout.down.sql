@@ -0,0 +1,3 @@
+ALTER TABLE banner_revisions DROP COLUMN rollout_percent;
+
+ALTER TABLE banners DROP COLUMN rollout_percent;
@@ -0,0 +1,4 @@
+ALTER TABLE banners ADD COLUMN rollout_percent INTEGER NOT NULL DEFAULT 100
+    CHECK (rollout_percent BETWEEN 0 AND 100);
+
+ALTER TABLE banner_revisions ADD COLUMN rollout_percent INTEGER NOT NULL DEFAULT 100;
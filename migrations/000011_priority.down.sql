@@ -0,0 +1,35 @@
+DROP INDEX banner_tags_tag_id_feature_id_priority_key;
+
+-- Banners that share a (tag, feature) pair at different priorities have to be
+-- fixed by hand: the migration stops and lists them instead of dropping tags.
+DO
+$$
+    DECLARE
+        conflicts TEXT;
+    BEGIN
+        SELECT string_agg(format('tag %s, feature %s: banners %s', tag_id, feature_id, banner_ids), '; ')
+        INTO conflicts
+        FROM (SELECT tag_id, feature_id, array_agg(banner_id ORDER BY banner_id) AS banner_ids
+              FROM banner_tags
+              GROUP BY tag_id, feature_id
+              HAVING count(*) > 1) c;
+
+        IF conflicts IS NOT NULL THEN
+            RAISE EXCEPTION 'banners share tag and feature pairs: %', conflicts;
+        END IF;
+    END
+$$;
+
+CREATE UNIQUE INDEX banner_tags_tag_id_feature_id_key ON banner_tags (tag_id, feature_id);
+
+ALTER TABLE banners ADD CONSTRAINT banners_id_feature_id_key UNIQUE (id, feature_id);
+
+ALTER TABLE banner_tags DROP CONSTRAINT banner_tags_banner_id_feature_id_priority_fkey;
+ALTER TABLE banner_tags ADD CONSTRAINT banner_tags_banner_id_feature_id_fkey
+    FOREIGN KEY (banner_id, feature_id) REFERENCES banners (id, feature_id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE banner_tags DROP COLUMN priority;
+
+ALTER TABLE banners DROP CONSTRAINT banners_id_feature_id_priority_key;
+
+ALTER TABLE banner_revisions DROP COLUMN priority;
+ALTER TABLE banners DROP COLUMN priority;
@@ -0,0 +1,16 @@
+ALTER TABLE banners ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE banner_revisions ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE banners ADD CONSTRAINT banners_id_feature_id_priority_key UNIQUE (id, feature_id, priority);
+
+ALTER TABLE banner_tags ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE banner_tags DROP CONSTRAINT banner_tags_banner_id_feature_id_fkey;
+ALTER TABLE banner_tags ADD CONSTRAINT banner_tags_banner_id_feature_id_priority_fkey
+    FOREIGN KEY (banner_id, feature_id, priority) REFERENCES banners (id, feature_id, priority)
+        ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE banners DROP CONSTRAINT banners_id_feature_id_key;
+
+-- Several banners may now share a placement as long as their priorities differ.
+DROP INDEX banner_tags_tag_id_feature_id_key;
+CREATE UNIQUE INDEX banner_tags_tag_id_feature_id_priority_key ON banner_tags (tag_id, feature_id, priority);
@@ -0,0 +1,22 @@
+ALTER TABLE features DROP COLUMN content_schema;
+
+ALTER TABLE banner_variants ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN text TEXT NOT NULL DEFAULT '',
+    ADD COLUMN url VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE banner_variants SET title = COALESCE(content->>'title', ''), text = COALESCE(content->>'text', ''),
+    url = COALESCE(content->>'url', '');
+ALTER TABLE banner_variants DROP COLUMN content;
+
+ALTER TABLE banner_revisions ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN text TEXT NOT NULL DEFAULT '',
+    ADD COLUMN url VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE banner_revisions SET title = COALESCE(content->>'title', ''), text = COALESCE(content->>'text', ''),
+    url = COALESCE(content->>'url', '');
+ALTER TABLE banner_revisions DROP COLUMN content;
+
+ALTER TABLE banners ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN text TEXT NOT NULL DEFAULT '',
+    ADD COLUMN url VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE banners SET title = COALESCE(content->>'title', ''), text = COALESCE(content->>'text', ''),
+    url = COALESCE(content->>'url', '');
+ALTER TABLE banners DROP COLUMN content;
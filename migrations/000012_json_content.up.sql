@@ -0,0 +1,16 @@
+ALTER TABLE banners ADD COLUMN content JSONB;
+UPDATE banners SET content = jsonb_build_object('title', title, 'text', text, 'url', url);
+ALTER TABLE banners ALTER COLUMN content SET NOT NULL;
+ALTER TABLE banners DROP COLUMN title, DROP COLUMN text, DROP COLUMN url;
+
+ALTER TABLE banner_revisions ADD COLUMN content JSONB;
+UPDATE banner_revisions SET content = jsonb_build_object('title', title, 'text', text, 'url', url);
+ALTER TABLE banner_revisions ALTER COLUMN content SET NOT NULL;
+ALTER TABLE banner_revisions DROP COLUMN title, DROP COLUMN text, DROP COLUMN url;
+
+ALTER TABLE banner_variants ADD COLUMN content JSONB;
+UPDATE banner_variants SET content = jsonb_build_object('title', title, 'text', text, 'url', url);
+ALTER TABLE banner_variants ALTER COLUMN content SET NOT NULL;
+ALTER TABLE banner_variants DROP COLUMN title, DROP COLUMN text, DROP COLUMN url;
+
+ALTER TABLE features ADD COLUMN content_schema JSONB;
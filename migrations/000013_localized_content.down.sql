@@ -0,0 +1,2 @@
+ALTER TABLE banner_revisions DROP COLUMN localized_content;
+ALTER TABLE banners DROP COLUMN localized_content;
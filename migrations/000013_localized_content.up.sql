@@ -0,0 +1,2 @@
+ALTER TABLE banners ADD COLUMN localized_content JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE banner_revisions ADD COLUMN localized_content JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+ALTER TABLE banner_revisions DROP COLUMN targeting;
+ALTER TABLE banners DROP COLUMN targeting;
@@ -0,0 +1,2 @@
+ALTER TABLE banners ADD COLUMN targeting JSONB;
+ALTER TABLE banner_revisions ADD COLUMN targeting JSONB;
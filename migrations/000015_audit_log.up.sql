@@ -0,0 +1,13 @@
+CREATE TABLE audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    actor_id   INTEGER     NOT NULL,
+    action     VARCHAR(32) NOT NULL,
+    banner_id  INTEGER     NOT NULL,
+    diff       JSONB       NOT NULL DEFAULT '{}',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_banner_id_created_at_idx ON audit_log (banner_id, created_at);
+CREATE INDEX audit_log_actor_id_created_at_idx ON audit_log (actor_id, created_at);
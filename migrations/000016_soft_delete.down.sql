@@ -0,0 +1,4 @@
+DELETE FROM banners WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS banners_deleted_at_idx;
+ALTER TABLE banners DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE banners ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX banners_deleted_at_idx ON banners (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS banners_status_idx;
+ALTER TABLE banners DROP COLUMN status;
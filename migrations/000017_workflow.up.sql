@@ -0,0 +1,4 @@
+ALTER TABLE banners ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published';
+ALTER TABLE banners ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX banners_status_idx ON banners (status);
@@ -0,0 +1 @@
+ALTER TABLE banners ADD COLUMN row_version INTEGER NOT NULL DEFAULT 1;
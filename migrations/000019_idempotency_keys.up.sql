@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys
+(
+    user_id     INTEGER      NOT NULL,
+    key         VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64)     NOT NULL,
+    status_code INTEGER      NOT NULL DEFAULT 0,
+    header      JSONB        NOT NULL DEFAULT '{}',
+    body        BYTEA,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys (created_at);
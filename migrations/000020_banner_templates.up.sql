@@ -0,0 +1,17 @@
+CREATE TABLE banner_templates
+(
+    id                       SERIAL       PRIMARY KEY,
+    name                     VARCHAR(255) NOT NULL UNIQUE,
+    description              TEXT         NOT NULL DEFAULT '',
+    feature_id               INTEGER      NOT NULL,
+    content                  JSONB        NOT NULL,
+    localized_content        JSONB        NOT NULL DEFAULT '{}',
+    targeting                JSONB,
+    is_active                BOOLEAN      NOT NULL DEFAULT false,
+    max_impressions_per_user INTEGER,
+    impression_period        INTEGER,
+    rollout_percent          INTEGER,
+    priority                 INTEGER      NOT NULL DEFAULT 0,
+    created_at               TIMESTAMP    NOT NULL,
+    updated_at               TIMESTAMP    NOT NULL
+);
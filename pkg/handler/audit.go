@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"banner"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+func (h *Handler) getAuditEntries(c *gin.Context) {
+	filter, err := getAuditFilter(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can get audit log")
+		return
+	}
+
+	type getAuditEntriesResponse struct {
+		Data []banner.AuditEntry `json:"data"`
+	}
+
+	entries, err := h.services.GetAuditEntries(filter)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getAuditEntriesResponse{
+		Data: entries,
+	})
+}
+
+func getAuditFilter(c *gin.Context) (banner.AuditFilter, error) {
+	filter := banner.AuditFilter{Limit: defaultAuditLimit}
+	var err error
+
+	if filter.ActorId, err = getIntQuery(c, "actor_id"); err != nil {
+		return filter, err
+	}
+
+	if filter.BannerId, err = getIntQuery(c, "banner_id"); err != nil {
+		return filter, err
+	}
+
+	if filter.From, err = getTimeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+
+	if filter.To, err = getTimeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+
+	limit, err := getIntQuery(c, "limit")
+	if err != nil {
+		return filter, err
+	}
+	if limit != nil {
+		if *limit < 1 || *limit > maxAuditLimit {
+			return filter, errors.New("limit must be between 1 and 1000")
+		}
+		filter.Limit = *limit
+	}
+
+	offset, err := getIntQuery(c, "offset")
+	if err != nil {
+		return filter, err
+	}
+	if offset != nil {
+		if *offset < 0 {
+			return filter, errors.New("offset must not be negative")
+		}
+		filter.Offset = *offset
+	}
+
+	return filter, nil
+}
+
+func getTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New("invalid " + key + " param, expected RFC 3339 time")
+	}
+
+	return &t, nil
+}
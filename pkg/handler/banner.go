@@ -3,16 +3,53 @@ package handler
 import (
 	"banner"
 	"database/sql"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type createBannerInput struct {
-	TagsIds   []int          `json:"tag_ids" binding:"required"`
-	FeatureId int            `json:"feature_id" binding:"required"`
-	Content   banner.Content `json:"content" binding:"required"`
-	IsActive  bool           `json:"is_active"`
+	TagsIds               []int                   `json:"tag_ids" binding:"required"`
+	FeatureId             int                     `json:"feature_id" binding:"required"`
+	Content               banner.Content          `json:"content" binding:"required"`
+	LocalizedContent      banner.LocalizedContent `json:"localized_content"`
+	Targeting             *banner.Rule            `json:"targeting"`
+	IsActive              bool                    `json:"is_active"`
+	StartsAt              *time.Time              `json:"starts_at"`
+	EndsAt                *time.Time              `json:"ends_at"`
+	MaxImpressionsPerUser *int                    `json:"max_impressions_per_user" binding:"omitempty,min=1"`
+	ImpressionPeriod      *int                    `json:"impression_period" binding:"omitempty,min=1"`
+	RolloutPercent        *int                    `json:"rollout_percent" binding:"omitempty,min=0,max=100"`
+	Priority              int                     `json:"priority"`
+	Variants              []banner.Variant        `json:"variants" binding:"omitempty,dive"`
+}
+
+func (input createBannerInput) toBanner(status, createdAt string) banner.Banner {
+	rolloutPercent := 100
+	if input.RolloutPercent != nil {
+		rolloutPercent = *input.RolloutPercent
+	}
+
+	return banner.Banner{
+		TagIds:                input.TagsIds,
+		FeatureId:             input.FeatureId,
+		Content:               input.Content,
+		LocalizedContent:      input.LocalizedContent,
+		Targeting:             input.Targeting,
+		IsActive:              input.IsActive,
+		StartsAt:              input.StartsAt,
+		EndsAt:                input.EndsAt,
+		MaxImpressionsPerUser: input.MaxImpressionsPerUser,
+		ImpressionPeriod:      input.ImpressionPeriod,
+		RolloutPercent:        rolloutPercent,
+		Priority:              input.Priority,
+		Variants:              input.Variants,
+		Status:                status,
+		CreatedAt:             createdAt,
+		UpdatedAt:             createdAt,
+	}
 }
 
 func (h *Handler) createBanner(c *gin.Context) {
@@ -28,56 +65,137 @@ func (h *Handler) createBanner(c *gin.Context) {
 		return
 	}
 
-	if role != "admin" {
-		newErrorResponse(c, http.StatusForbidden, "only admin can create banner")
+	if !hasRole(role, "admin", "editor") {
+		newErrorResponse(c, http.StatusForbidden, "only admin or editor can create banner")
 		return
 	}
 
-	exist, err := h.services.Banner.CheckBanner(input.TagsIds, input.FeatureId)
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	id, err := h.services.Banner.CreateBanner(input.toBanner(banner.StatusDraft, getTime()), actor)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"banner_id": id,
+	})
+}
+
+func (h *Handler) cloneBanner(c *gin.Context) {
+	var input banner.CloneBannerInput
 
-	if exist {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err = c.BindJSON(&input); err != nil {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	role, err := getUserRole(c)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	currentTime := getTime()
+	if !hasRole(role, "admin", "editor") {
+		newErrorResponse(c, http.StatusForbidden, "only admin or editor can clone banner")
+		return
+	}
 
-	banner := banner.Banner{
-		TagIds:    input.TagsIds,
-		FeatureId: input.FeatureId,
-		Content:   input.Content,
-		IsActive:  input.IsActive,
-		CreatedAt: currentTime,
-		UpdatedAt: currentTime,
+	actor, err := getActor(c)
+	if err != nil {
+		return
 	}
 
-	id, err := h.services.Banner.CreateBanner(banner)
+	input.Status = banner.StatusDraft
+	input.CreatedAt = getTime()
+
+	cloneId, err := h.services.Banner.CloneBanner(id, input, actor)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, map[string]interface{}{
-		"banner_id": id,
+		"banner_id": cloneId,
 	})
 }
 
-type updateContent struct {
-	Title string `json:"title"`
-	Text  string `json:"text"`
-	Url   string `json:"url"`
+// nullable tells a field sent as null, which clears the value, from a field
+// that wasn't sent at all.
+type nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	n.Value = new(T)
+	return json.Unmarshal(data, n.Value)
+}
+
+func (n nullable[T]) apply(value **T) {
+	if n.Set {
+		*value = n.Value
+	}
 }
 
 type updateBannerInput struct {
-	TagsIds   []int         `json:"tag_ids"`
-	FeatureId int           `json:"feature_id"`
-	Content   updateContent `json:"content"`
-	IsActive  bool          `json:"is_active"`
+	TagsIds               []int                   `json:"tag_ids"`
+	FeatureId             int                     `json:"feature_id"`
+	Content               banner.Content          `json:"content"`
+	LocalizedContent      banner.LocalizedContent `json:"localized_content"`
+	Targeting             *banner.Rule            `json:"targeting"`
+	IsActive              bool                    `json:"is_active"`
+	StartsAt              nullable[time.Time]     `json:"starts_at"`
+	EndsAt                nullable[time.Time]     `json:"ends_at"`
+	MaxImpressionsPerUser nullable[int]           `json:"max_impressions_per_user"`
+	ImpressionPeriod      nullable[int]           `json:"impression_period"`
+	RolloutPercent        *int                    `json:"rollout_percent" binding:"omitempty,min=0,max=100"`
+	Priority              *int                    `json:"priority"`
+	Variants              []banner.Variant        `json:"variants" binding:"omitempty,dive"`
+}
+
+func (h *Handler) getBannerById(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, "admin", "editor", "approver") {
+		newErrorResponse(c, http.StatusForbidden, "only admin, editor or approver can get banner")
+		return
+	}
+
+	b, err := h.services.GetBannerById(id)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.Header(etagHeader, formatETag(b.RowVersion))
+	c.JSON(http.StatusOK, b)
 }
 
 func (h *Handler) updateBanner(c *gin.Context) {
@@ -94,14 +212,29 @@ func (h *Handler) updateBanner(c *gin.Context) {
 		return
 	}
 
+	if isBelowOne(input.MaxImpressionsPerUser.Value) || isBelowOne(input.ImpressionPeriod.Value) {
+		newErrorResponse(c, http.StatusBadRequest, "max_impressions_per_user and impression_period must be at least 1")
+		return
+	}
+
 	role, err := getUserRole(c)
 	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if role != "admin" {
-		newErrorResponse(c, http.StatusForbidden, "only admin can update banner")
+	if !hasRole(role, "admin", "editor") {
+		newErrorResponse(c, http.StatusForbidden, "only admin or editor can update banner")
+		return
+	}
+
+	rowVersion, err := getIfMatch(c)
+	if err != nil {
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
 		return
 	}
 
@@ -115,29 +248,49 @@ func (h *Handler) updateBanner(c *gin.Context) {
 		return
 	}
 
+	// Every edit has to be reviewed again, so it takes a published banner off
+	// the air until it is published again. Only an admin can do that.
+	if role == "editor" && oldBanner.Status == banner.StatusPublished {
+		newErrorResponse(c, http.StatusConflict, "only admin can edit published banner")
+		return
+	}
+
 	currentTime := getTime()
 
-	content := banner.Content{
-		Title: input.Content.Title,
-		Text:  input.Content.Text,
-		Url:   input.Content.Url,
+	b := banner.Banner{
+		TagIds:           input.TagsIds,
+		FeatureId:        input.FeatureId,
+		Content:          input.Content,
+		LocalizedContent: input.LocalizedContent,
+		Targeting:        input.Targeting,
+		IsActive:         input.IsActive,
+		Variants:         input.Variants,
+		UpdatedAt:        currentTime,
+	}
+
+	updatedBanner := getUpdatedBanner(oldBanner, b)
+	input.StartsAt.apply(&updatedBanner.StartsAt)
+	input.EndsAt.apply(&updatedBanner.EndsAt)
+	input.MaxImpressionsPerUser.apply(&updatedBanner.MaxImpressionsPerUser)
+	input.ImpressionPeriod.apply(&updatedBanner.ImpressionPeriod)
+	if input.RolloutPercent != nil {
+		updatedBanner.RolloutPercent = *input.RolloutPercent
 	}
 
-	banner := banner.Banner{
-		TagIds:    input.TagsIds,
-		FeatureId: input.FeatureId,
-		Content:   content,
-		IsActive:  input.IsActive,
-		UpdatedAt: currentTime,
+	if input.Priority != nil {
+		updatedBanner.Priority = *input.Priority
 	}
 
-	updatedBanner := getUpdatedBanner(oldBanner, banner)
+	updatedBanner.Status = banner.StatusDraft
+	updatedBanner.RowVersion = rowVersion
 
-	if err = h.services.Banner.UpdateBannerById(id, updatedBanner); err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+	if err = h.services.Banner.UpdateBannerById(id, updatedBanner, actor); err != nil {
+		newServiceErrorResponse(c, err)
 		return
 	}
 
+	// The update succeeds only from rowVersion and bumps it by one.
+	c.Header(etagHeader, formatETag(rowVersion+1))
 	c.JSON(http.StatusOK, map[string]interface{}{})
 }
 
@@ -159,16 +312,213 @@ func (h *Handler) deleteBanner(c *gin.Context) {
 		return
 	}
 
-	if err = h.services.DeleteBannerById(id); err != nil {
+	rowVersion, err := getIfMatch(c)
+	if err != nil {
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	if err = h.services.DeleteBannerById(id, rowVersion, actor); err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+		case err == banner.ErrVersionMismatch:
+			newErrorResponse(c, http.StatusPreconditionFailed, err.Error())
+		default:
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusNoContent, map[string]interface{}{})
+}
+
+func (h *Handler) restoreBanner(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can restore banner")
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	if err = h.services.RestoreBannerById(id, getTime(), actor); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{})
+}
+
+func (h *Handler) deleteBanners(c *gin.Context) {
+	var input banner.BulkDeleteInput
+	var err error
+
+	if input.TagId, err = getIntQuery(c, "tag_id"); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.FeatureId, err = getIntQuery(c, "feature_id"); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.TagId == nil && input.FeatureId == nil {
+		newErrorResponse(c, http.StatusBadRequest, "tag_id or feature_id param is required")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can delete banners")
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	jobId, err := h.services.Job.CreateBulkDeleteJob(input, actor)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]interface{}{
+		"job_id": jobId,
+	})
+}
+
+func (h *Handler) getBannerVersions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, "admin", "editor", "approver") {
+		newErrorResponse(c, http.StatusForbidden, "only admin, editor or approver can get banner versions")
+		return
+	}
+
+	type getBannerVersionsResponse struct {
+		Data []banner.Revision `json:"data"`
+	}
+
+	revisions, err := h.services.GetBannerRevisions(id)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			newErrorResponse(c, http.StatusNotFound, err.Error())
 			return
 		}
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, getBannerVersionsResponse{
+		Data: revisions,
+	})
+}
+
+func (h *Handler) activateBannerVersion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid version param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusNoContent, map[string]interface{}{})
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can activate banner version")
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	if err = h.services.ActivateBannerRevision(id, version, getTime(), actor); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{})
+}
+
+func (h *Handler) getVariantReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can get variant report")
+		return
+	}
+
+	type getVariantReportResponse struct {
+		Data []banner.VariantReport `json:"data"`
+	}
+
+	report, err := h.services.GetVariantReport(id)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getVariantReportResponse{
+		Data: report,
+	})
 }
 
 func (h *Handler) getUserBanner(c *gin.Context) {
@@ -178,6 +528,8 @@ func (h *Handler) getUserBanner(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	input.Locales = getLocales(c)
+	input.Nickname = c.GetString(userCtxNickname)
 
 	role, err := getUserRole(c)
 	if err != nil {
@@ -185,7 +537,12 @@ func (h *Handler) getUserBanner(c *gin.Context) {
 		return
 	}
 
-	content, err := h.services.GetUserBanner(input, role)
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	userBanner, err := h.services.GetUserBanner(input, userId, role)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			newErrorResponse(c, http.StatusNotFound, err.Error())
@@ -195,7 +552,57 @@ func (h *Handler) getUserBanner(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, content)
+	// The body is the banner content as stored, so the ids travel in headers.
+	c.Header(bannerIdHeader, strconv.Itoa(userBanner.BannerId))
+	if userBanner.VariantId != 0 {
+		c.Header(variantIdHeader, strconv.Itoa(userBanner.VariantId))
+	}
+	if userBanner.Locale != "" {
+		c.Header(contentLanguageHeader, userBanner.Locale)
+	}
+
+	c.JSON(http.StatusOK, userBanner.Content)
+}
+
+type getUserBannersInput struct {
+	banner.UserBannerInput
+	Limit int `json:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+func (h *Handler) getUserBanners(c *gin.Context) {
+	input := getUserBannersInput{Limit: 10}
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.Locales = getLocales(c)
+	input.Nickname = c.GetString(userCtxNickname)
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	type getUserBannersResponse struct {
+		Data []banner.UserBanner `json:"data"`
+	}
+
+	banners, err := h.services.GetUserBanners(input.UserBannerInput, input.Limit, userId, role)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getUserBannersResponse{
+		Data: banners,
+	})
 }
 
 func (h *Handler) getAllBanners(c *gin.Context) {
@@ -212,22 +619,26 @@ func (h *Handler) getAllBanners(c *gin.Context) {
 		return
 	}
 
-	if role != "admin" {
-		newErrorResponse(c, http.StatusForbidden, "only admin can get all banners")
+	if !hasRole(role, "admin", "editor", "approver") {
+		newErrorResponse(c, http.StatusForbidden, "only admin, editor or approver can get all banners")
 		return
 	}
 
 	type getAllBannersResponse struct {
-		Data []banner.Banner `json:" "`
+		Data       []banner.Banner `json:"data"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+		Total      int             `json:"total"`
 	}
 
-	banners, err := h.services.GetAllBanners(input)
+	page, err := h.services.GetAllBanners(input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newServiceErrorResponse(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, getAllBannersResponse{
-		Data: banners,
+		Data:       page.Banners,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
 	})
 }
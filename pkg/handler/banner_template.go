@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"banner"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) createBannerTemplate(c *gin.Context) {
+	var input banner.BannerTemplate
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can create template")
+		return
+	}
+
+	input.CreatedAt = getTime()
+	input.UpdatedAt = input.CreatedAt
+
+	id, err := h.services.BannerTemplate.CreateBannerTemplate(input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) getAllBannerTemplates(c *gin.Context) {
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, "admin", "editor", "approver") {
+		newErrorResponse(c, http.StatusForbidden, "only admin, editor or approver can get templates")
+		return
+	}
+
+	type getAllBannerTemplatesResponse struct {
+		Data []banner.BannerTemplate `json:"data"`
+	}
+
+	templates, err := h.services.BannerTemplate.GetAllBannerTemplates()
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllBannerTemplatesResponse{
+		Data: templates,
+	})
+}
+
+func (h *Handler) getBannerTemplateById(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, "admin", "editor", "approver") {
+		newErrorResponse(c, http.StatusForbidden, "only admin, editor or approver can get templates")
+		return
+	}
+
+	template, err := h.services.BannerTemplate.GetBannerTemplateById(id)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *Handler) updateBannerTemplate(c *gin.Context) {
+	var input banner.UpdateBannerTemplateInput
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err = c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can update template")
+		return
+	}
+
+	if err = h.services.BannerTemplate.UpdateBannerTemplate(id, input, getTime()); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{})
+}
+
+func (h *Handler) deleteBannerTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can delete template")
+		return
+	}
+
+	if err = h.services.BannerTemplate.DeleteBannerTemplate(id); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, map[string]interface{}{})
+}
+
+func (h *Handler) instantiateBannerTemplate(c *gin.Context) {
+	var input banner.InstantiateTemplateInput
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err = c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, "admin", "editor") {
+		newErrorResponse(c, http.StatusForbidden, "only admin or editor can create banner")
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	input.Status = banner.StatusDraft
+	input.CreatedAt = getTime()
+
+	bannerId, err := h.services.BannerTemplate.InstantiateBannerTemplate(id, input, actor)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"banner_id": bannerId,
+	})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"banner"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) clickBanner(c *gin.Context) {
+	var input banner.ClickInput
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err = c.BindJSON(&input); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	if err = h.services.Event.RecordClick(id, input, userId); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]interface{}{})
+}
+
+func (h *Handler) getBannerStats(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can get banner stats")
+		return
+	}
+
+	type getBannerStatsResponse struct {
+		Data []banner.DailyStats `json:"data"`
+	}
+
+	stats, err := h.services.GetBannerStats(id)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getBannerStatsResponse{
+		Data: stats,
+	})
+}
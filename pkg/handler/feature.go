@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"banner"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) createFeature(c *gin.Context) {
+	var input banner.Feature
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can create feature")
+		return
+	}
+
+	input.CreatedAt = getTime()
+
+	id, err := h.services.Feature.CreateFeature(input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) getAllFeatures(c *gin.Context) {
+	type getAllFeaturesResponse struct {
+		Data []banner.Feature `json:"data"`
+	}
+
+	features, err := h.services.Feature.GetAllFeatures()
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, getAllFeaturesResponse{
+		Data: features,
+	})
+}
+
+func (h *Handler) getFeatureById(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	feature, err := h.services.Feature.GetFeatureById(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, feature)
+}
+
+func (h *Handler) updateFeature(c *gin.Context) {
+	var input banner.UpdateFeatureInput
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	if err = c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can update feature")
+		return
+	}
+
+	if err = h.services.Feature.UpdateFeature(id, input); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{})
+}
+
+func (h *Handler) deleteFeature(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can delete feature")
+		return
+	}
+
+	if err = h.services.Feature.DeleteFeature(id); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, map[string]interface{}{})
+}
@@ -15,6 +15,7 @@ func NewHandler(services *service.Service) *Handler {
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	router.Use(setRequestId)
 
 	auth := router.Group("")
 	{
@@ -22,14 +23,62 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		auth.GET("/login", h.login)
 	}
 
-	banner := router.Group("", h.userIdentity)
+	banner := router.Group("", h.userIdentity, h.idempotency)
 	{
 		banner.POST("/banner", h.createBanner)
+		banner.GET("/banner/:id", h.getBannerById)
 		banner.PATCH("/banner/:id", h.updateBanner)
 		banner.DELETE("/banner/:id", h.deleteBanner)
+		banner.POST("/banner/:id/restore", h.restoreBanner)
+		banner.POST("/banner/:id/clone", h.cloneBanner)
+		banner.POST("/banner/:id/submit", h.submitBanner)
+		banner.POST("/banner/:id/approve", h.approveBanner)
+		banner.POST("/banner/:id/reject", h.rejectBanner)
+		banner.POST("/banner/:id/publish", h.publishBanner)
+		banner.POST("/banner/:id/archive", h.archiveBanner)
+		banner.DELETE("/banner", h.deleteBanners)
+		banner.GET("/banner/:id/versions", h.getBannerVersions)
+		banner.POST("/banner/:id/versions/:version/activate", h.activateBannerVersion)
+		banner.GET("/banner/:id/variants/report", h.getVariantReport)
+		banner.POST("/banner/:id/click", h.clickBanner)
+		banner.GET("/banner/:id/stats", h.getBannerStats)
 		banner.GET("/banner", h.getAllBanners)
+		banner.GET("/banner/export", h.exportBanners)
+		banner.POST("/banner/import", h.importBanners)
+		banner.POST("/banner/targeting/validate", h.validateTargeting)
+		banner.POST("/banner/simulate", h.simulateBanner)
 		banner.GET("/user_banner", h.getUserBanner)
+		banner.GET("/user_banners", h.getUserBanners)
+		banner.GET("/jobs/:id", h.getJob)
+		banner.GET("/audit", h.getAuditEntries)
+	}
+
+	features := router.Group("/features", h.userIdentity, h.idempotency)
+	{
+		features.POST("", h.createFeature)
+		features.GET("", h.getAllFeatures)
+		features.GET("/:id", h.getFeatureById)
+		features.PATCH("/:id", h.updateFeature)
+		features.DELETE("/:id", h.deleteFeature)
+	}
 
+	tags := router.Group("/tags", h.userIdentity, h.idempotency)
+	{
+		tags.POST("", h.createTag)
+		tags.GET("", h.getAllTags)
+		tags.GET("/:id", h.getTagById)
+		tags.PATCH("/:id", h.updateTag)
+		tags.DELETE("/:id", h.deleteTag)
+	}
+
+	templates := router.Group("/templates", h.userIdentity, h.idempotency)
+	{
+		templates.POST("", h.createBannerTemplate)
+		templates.GET("", h.getAllBannerTemplates)
+		templates.GET("/:id", h.getBannerTemplateById)
+		templates.PATCH("/:id", h.updateBannerTemplate)
+		templates.DELETE("/:id", h.deleteBannerTemplate)
+		templates.POST("/:id/banners", h.instantiateBannerTemplate)
 	}
 
 	return router
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"banner"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// bodyRecorder keeps a copy of the response body for idempotent replays.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotency makes mutating requests sent with an Idempotency-Key safe to
+// retry: the first response for a key is stored and replayed for repeats of
+// the same request. Server errors are not stored, so such requests can be
+// retried for real.
+func (h *Handler) idempotency(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		newErrorResponse(c, http.StatusBadRequest, "Idempotency-Key must not be longer than 255 characters")
+		return
+	}
+
+	userId, err := getUserId(c)
+	if err != nil {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	record, err := h.services.BeginRequest(userId, key, requestFingerprint(c, body))
+	switch {
+	case errors.Is(err, banner.ErrKeyReused):
+		newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	case errors.Is(err, banner.ErrKeyInProgress):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if record != nil {
+		for name, values := range record.Header {
+			if name != requestIdHeader {
+				c.Writer.Header()[name] = values
+			}
+		}
+		c.Header(idempotentReplayHeader, "true")
+		c.Data(record.StatusCode, record.Header.Get("Content-Type"), record.Body)
+		c.Abort()
+		return
+	}
+
+	// The key is released unless the response is stored, even when the handler
+	// panics, so the request can be retried.
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		if err := h.services.ReleaseRequest(userId, key); err != nil {
+			logrus.Errorf("failed to release idempotency key: %s", err.Error())
+		}
+	}()
+
+	recorder := &bodyRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	if recorder.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	err = h.services.CompleteRequest(banner.IdempotencyRecord{
+		UserId:     userId,
+		Key:        key,
+		StatusCode: recorder.Status(),
+		Header:     recorder.Header().Clone(),
+		Body:       recorder.body.Bytes(),
+	})
+	if err != nil {
+		logrus.Errorf("failed to store idempotent response: %s", err.Error())
+		return
+	}
+	completed = true
+}
+
+// requestFingerprint identifies what the request asks for: the route, the
+// precondition it was made under and its body.
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+	hash.Write([]byte(c.GetHeader(ifMatchHeader) + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
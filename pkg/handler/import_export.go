@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"banner"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	formatJSONL       = "jsonl"
+	formatCSV         = "csv"
+	maxImportRows     = 10000
+	maxImportLineSize = 1 << 20
+)
+
+var errTooManyRows = fmt.Errorf("import is limited to %d banners", maxImportRows)
+
+// exportColumns are the CSV columns, named after the banner's JSON fields.
+// Import reads the same columns, ignoring banner_id, created_at and
+// updated_at.
+var exportColumns = []string{"banner_id", "tag_ids", "feature_id", "status", "is_active", "priority",
+	"rollout_percent", "starts_at", "ends_at", "max_impressions_per_user", "impression_period", "content",
+	"localized_content", "targeting", "variants", "created_at", "updated_at"}
+
+// csvStringColumns hold plain text, the other columns hold JSON values.
+var csvStringColumns = map[string]bool{
+	"status": true, "starts_at": true, "ends_at": true, "created_at": true, "updated_at": true,
+}
+
+type exportWriter interface {
+	Write(b banner.Banner) error
+	Flush() error
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(b banner.Banner) error {
+	return w.encoder.Encode(b)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return nil
+}
+
+// csvWriter writes the header before the first banner, or on Flush when there
+// are none.
+type csvWriter struct {
+	writer  *csv.Writer
+	started bool
+}
+
+func (w *csvWriter) Write(b banner.Banner) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	record, err := csvRecord(b)
+	if err != nil {
+		return err
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Flush() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.writer.Write(exportColumns)
+}
+
+func (h *Handler) exportBanners(c *gin.Context) {
+	var input banner.FilterInput
+
+	if err := c.ShouldBindQuery(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, "admin", "editor", "approver") {
+		newErrorResponse(c, http.StatusForbidden, "only admin, editor or approver can export banners")
+		return
+	}
+
+	var writer exportWriter
+	var contentType string
+	format := c.DefaultQuery("format", formatJSONL)
+	switch format {
+	case formatJSONL:
+		writer = &jsonlWriter{encoder: json.NewEncoder(c.Writer)}
+		contentType = "application/x-ndjson"
+	case formatCSV:
+		writer = &csvWriter{writer: csv.NewWriter(c.Writer)}
+		contentType = "text/csv"
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "format must be one of jsonl, csv")
+		return
+	}
+
+	// Headers are sent with the first batch, so that errors found before it
+	// still get an error response.
+	writeHeaders := func() {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="banners.`+format+`"`)
+		c.Status(http.StatusOK)
+	}
+
+	err = h.services.ExportBanners(input, func(banners []banner.Banner) error {
+		if !c.Writer.Written() {
+			writeHeaders()
+		}
+
+		for _, b := range banners {
+			if err := writer.Write(b); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		if !c.Writer.Written() {
+			newServiceErrorResponse(c, err)
+			return
+		}
+		logrus.Errorf("failed to export banners: %s", err.Error())
+		return
+	}
+
+	if !c.Writer.Written() {
+		writeHeaders()
+	}
+	if err = writer.Flush(); err != nil {
+		logrus.Errorf("failed to export banners: %s", err.Error())
+	}
+}
+
+func (h *Handler) importBanners(c *gin.Context) {
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can import banners")
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid dry_run param")
+		return
+	}
+
+	var rows []banner.ImportRow
+	createdAt := getTime()
+	switch c.DefaultQuery("format", formatJSONL) {
+	case formatJSONL:
+		rows, err = readJSONLRows(c.Request.Body, createdAt)
+	case formatCSV:
+		rows, err = readCSVRows(c.Request.Body, createdAt)
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "format must be one of jsonl, csv")
+		return
+	}
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(rows) == 0 {
+		newErrorResponse(c, http.StatusBadRequest, "import has no banners")
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	result, err := h.services.ImportBanners(rows, dryRun, actor)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	switch {
+	case len(result.Errors) > 0:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case dryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+func readJSONLRows(body io.Reader, createdAt string) ([]banner.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxImportLineSize)
+
+	var rows []banner.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, errTooManyRows
+		}
+		rows = append(rows, parseImportRow(data, line, createdAt))
+	}
+
+	return rows, scanner.Err()
+}
+
+// readCSVRows reads banners from CSV with a header row naming the columns.
+func readCSVRows(body io.Reader, createdAt string) ([]banner.ImportRow, error) {
+	reader := csv.NewReader(body)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []banner.ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, errTooManyRows
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			rows = append(rows, banner.ImportRow{Line: parseErr.StartLine, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		data, err := csvRowJSON(header, record)
+		if err != nil {
+			rows = append(rows, banner.ImportRow{Line: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, parseImportRow(data, line, createdAt))
+	}
+
+	return rows, nil
+}
+
+// parseImportRow reads a banner from a JSON object. Imported banners start as
+// drafts whatever status the row has, so they go through review.
+func parseImportRow(data []byte, line int, createdAt string) banner.ImportRow {
+	row := banner.ImportRow{Line: line}
+
+	var input createBannerInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		row.Error = err.Error()
+		return row
+	}
+
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		row.Error = err.Error()
+		return row
+	}
+
+	row.Banner = input.toBanner(banner.StatusDraft, createdAt)
+	return row
+}
+
+func csvRecord(b banner.Banner) ([]string, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		value := fields[column]
+		if len(value) == 0 || string(value) == "null" {
+			continue
+		}
+
+		if csvStringColumns[column] {
+			var text string
+			if err = json.Unmarshal(value, &text); err != nil {
+				return nil, err
+			}
+			record[i] = text
+			continue
+		}
+		record[i] = string(value)
+	}
+
+	return record, nil
+}
+
+// csvRowJSON turns a CSV record into the JSON object of the banner, leaving
+// out empty cells.
+func csvRowJSON(header, record []string) ([]byte, error) {
+	fields := make(map[string]json.RawMessage, len(header))
+	for i, column := range header {
+		value := record[i]
+		if value == "" {
+			continue
+		}
+
+		if csvStringColumns[column] {
+			text, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			fields[column] = text
+			continue
+		}
+
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("invalid %s value", column)
+		}
+		fields[column] = json.RawMessage(value)
+	}
+
+	return json.Marshal(fields)
+}
@@ -2,21 +2,37 @@ package handler
 
 import (
 	"banner"
+	"banner/pkg/service"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	authorizationHeader = "Authorization"
-	userCtxId           = "userId"
-	userCtxRole         = "role"
+	authorizationHeader   = "Authorization"
+	acceptLanguageHeader  = "Accept-Language"
+	contentLanguageHeader = "Content-Language"
+	bannerIdHeader        = "X-Banner-Id"
+	variantIdHeader       = "X-Variant-Id"
+	requestIdHeader       = "X-Request-Id"
+	etagHeader            = "ETag"
+	ifMatchHeader         = "If-Match"
+	userCtxId             = "userId"
+	userCtxRole           = "role"
+	userCtxNickname       = "nickname"
+	requestIdCtx          = "requestId"
 )
 
+var requestIdPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
 func generatePasswordHash(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
@@ -36,6 +52,23 @@ func comparePasswordHash(hash, inputPassword string) error {
 	return bcrypt.CompareHashAndPassword(hashedPassword, inputPasswordBytes)
 }
 
+// setRequestId tags the request with the client's X-Request-Id, or a new one
+// when it is missing or malformed, and echoes it in the response.
+func setRequestId(c *gin.Context) {
+	requestId := c.GetHeader(requestIdHeader)
+	if !requestIdPattern.MatchString(requestId) {
+		bytes := make([]byte, 16)
+		if _, err := rand.Read(bytes); err != nil {
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		requestId = hex.EncodeToString(bytes)
+	}
+
+	c.Set(requestIdCtx, requestId)
+	c.Header(requestIdHeader, requestId)
+}
+
 func (h *Handler) userIdentity(c *gin.Context) {
 	header := c.GetHeader(authorizationHeader)
 	if header == "" {
@@ -49,13 +82,14 @@ func (h *Handler) userIdentity(c *gin.Context) {
 		return
 	}
 	//parse token
-	userId, role, err := h.services.Authorization.ParseToken(headerParts[1])
+	claims, err := h.services.Authorization.ParseToken(headerParts[1])
 	if err != nil {
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
 		return
 	}
-	c.Set(userCtxId, userId)
-	c.Set(userCtxRole, role)
+	c.Set(userCtxId, claims.UserId)
+	c.Set(userCtxRole, claims.Role)
+	c.Set(userCtxNickname, claims.Nickname)
 }
 
 func getUserRole(c *gin.Context) (string, error) {
@@ -74,6 +108,123 @@ func getUserRole(c *gin.Context) (string, error) {
 	return roleStr, nil
 }
 
+func getUserId(c *gin.Context) (int, error) {
+	id, ok := c.Get(userCtxId)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "user id not found")
+		return 0, errors.New("user id not found")
+	}
+
+	idInt, ok := id.(int)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "user id is of invalid type")
+		return 0, errors.New("user id is of invalid type")
+	}
+
+	return idInt, nil
+}
+
+// getActor describes the authenticated user for audited mutations.
+func getActor(c *gin.Context) (banner.Actor, error) {
+	userId, err := getUserId(c)
+	if err != nil {
+		return banner.Actor{}, err
+	}
+
+	return banner.Actor{UserId: userId, RequestId: c.GetString(requestIdCtx)}, nil
+}
+
+func hasRole(role string, roles ...string) bool {
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+func formatETag(rowVersion int) string {
+	return `"` + strconv.Itoa(rowVersion) + `"`
+}
+
+// getIfMatch returns the banner row version the client expects from the
+// If-Match header, which is required for changing a banner.
+func getIfMatch(c *gin.Context) (int, error) {
+	value := c.GetHeader(ifMatchHeader)
+	if value == "" {
+		newErrorResponse(c, http.StatusPreconditionRequired, "If-Match header is required")
+		return 0, errors.New("If-Match header is required")
+	}
+
+	rowVersion, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(value, "W/"), `"`))
+	if err != nil {
+		newErrorResponse(c, http.StatusPreconditionFailed, banner.ErrVersionMismatch.Error())
+		return 0, banner.ErrVersionMismatch
+	}
+
+	return rowVersion, nil
+}
+
+func getIntQuery(c *gin.Context, key string) (*int, error) {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return nil, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, errors.New("invalid " + key + " param")
+	}
+
+	return &number, nil
+}
+
+// getLocales returns the locales the user accepts, most preferred first. The
+// lang query parameter wins over the Accept-Language header.
+func getLocales(c *gin.Context) []string {
+	var locales []string
+	if lang := c.Query("lang"); lang != "" {
+		locales = append(locales, lang)
+	}
+
+	type weightedLocale struct {
+		locale  string
+		quality float64
+	}
+
+	var weighted []weightedLocale
+	for _, part := range strings.Split(c.GetHeader(acceptLanguageHeader), ",") {
+		params := strings.Split(part, ";")
+		locale := strings.TrimSpace(params[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if quality > 0 {
+			weighted = append(weighted, weightedLocale{locale: locale, quality: quality})
+		}
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].quality > weighted[j].quality
+	})
+	for _, w := range weighted {
+		locales = append(locales, w.locale)
+	}
+
+	return locales
+}
+
 func getTime() string {
 	currentTime := time.Now().UTC()
 	formattedTime := currentTime.Format("2006-01-02T15:04:05.999Z")
@@ -96,20 +247,47 @@ func getUpdatedBanner(oldBanner banner.Banner, inputBanner banner.Banner) banner
 		updatedBanner.IsActive = inputBanner.IsActive
 	}
 
+	// Variants are replaced only when the request carries them.
+	updatedBanner.Variants = inputBanner.Variants
+
 	//Content
-	if inputBanner.Content.Title != "" {
-		updatedBanner.Content.Title = inputBanner.Content.Title
+	if len(inputBanner.Content) > 0 {
+		content := make(banner.Content, len(oldBanner.Content)+len(inputBanner.Content))
+		for key, value := range oldBanner.Content {
+			content[key] = value
+		}
+		for key, value := range inputBanner.Content {
+			content[key] = value
+		}
+		updatedBanner.Content = content
 	}
 
-	if inputBanner.Content.Text != "" {
-		updatedBanner.Content.Text = inputBanner.Content.Text
+	if inputBanner.Targeting != nil {
+		updatedBanner.Targeting = inputBanner.Targeting
 	}
 
-	if inputBanner.Content.Url != "" {
-		updatedBanner.Content.Url = inputBanner.Content.Url
+	// Translations are merged per normalized locale, a null translation removes it.
+	if inputBanner.LocalizedContent != nil {
+		localized := make(banner.LocalizedContent, len(oldBanner.LocalizedContent))
+		for locale, content := range oldBanner.LocalizedContent {
+			localized[service.NormalizeLocale(locale)] = content
+		}
+		for locale, content := range inputBanner.LocalizedContent {
+			locale = service.NormalizeLocale(locale)
+			if content == nil {
+				delete(localized, locale)
+				continue
+			}
+			localized[locale] = content
+		}
+		updatedBanner.LocalizedContent = localized
 	}
 
 	updatedBanner.UpdatedAt = inputBanner.UpdatedAt
 
 	return updatedBanner
 }
+
+func isBelowOne(value *int) bool {
+	return value != nil && *value < 1
+}
@@ -1,15 +1,58 @@
 package handler
 
 import (
+	"banner"
+	"database/sql"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"net/http"
 )
 
 type errorResponse struct {
 	Message string `json:"error"`
 }
 
+type conflictResponse struct {
+	Message   string `json:"error"`
+	BannerIds []int  `json:"banner_ids"`
+}
+
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
 	logrus.Errorf(message)
 	c.AbortWithStatusJSON(statusCode, errorResponse{message})
 }
+
+func newServiceErrorResponse(c *gin.Context, err error) {
+	var unknownIdsErr *banner.UnknownIdsError
+	var conflictErr *banner.ConflictError
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, banner.ErrInUse), errors.Is(err, banner.ErrInvalidAction), errors.Is(err, banner.ErrNameTaken):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, banner.ErrVersionMismatch):
+		newErrorResponse(c, http.StatusPreconditionFailed, err.Error())
+	case errors.As(err, &conflictErr):
+		logrus.Errorf(err.Error())
+		c.AbortWithStatusJSON(http.StatusConflict, conflictResponse{err.Error(), conflictErr.BannerIds})
+	case errors.As(err, &unknownIdsErr),
+		errors.Is(err, banner.ErrInvalidSchedule),
+		errors.Is(err, banner.ErrInvalidState),
+		errors.Is(err, banner.ErrInvalidStatus),
+		errors.Is(err, banner.ErrInvalidSort),
+		errors.Is(err, banner.ErrInvalidCursor),
+		errors.Is(err, banner.ErrInvalidLimit),
+		errors.Is(err, banner.ErrInvalidOffset),
+		errors.Is(err, banner.ErrInvalidCap),
+		errors.Is(err, banner.ErrInvalidSchema),
+		errors.Is(err, banner.ErrInvalidContent),
+		errors.Is(err, banner.ErrInvalidLocale),
+		errors.Is(err, banner.ErrInvalidTemplate),
+		errors.Is(err, banner.ErrInvalidRule):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
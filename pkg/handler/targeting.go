@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"banner"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (h *Handler) validateTargeting(c *gin.Context) {
+	var input banner.ValidateRuleInput
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can validate targeting rules")
+		return
+	}
+
+	check, err := h.services.ValidateTargeting(input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, check)
+}
+
+func (h *Handler) simulateBanner(c *gin.Context) {
+	input := banner.SimulateInput{Role: "user"}
+
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if role != "admin" {
+		newErrorResponse(c, http.StatusForbidden, "only admin can simulate banner selection")
+		return
+	}
+
+	simulation, err := h.services.Simulate(input)
+	if err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"banner"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (h *Handler) submitBanner(c *gin.Context) {
+	h.changeBannerStatus(c, banner.ActionSubmit, "admin", "editor")
+}
+
+func (h *Handler) approveBanner(c *gin.Context) {
+	h.changeBannerStatus(c, banner.ActionApprove, "admin", "approver")
+}
+
+func (h *Handler) rejectBanner(c *gin.Context) {
+	h.changeBannerStatus(c, banner.ActionReject, "admin", "approver")
+}
+
+func (h *Handler) publishBanner(c *gin.Context) {
+	h.changeBannerStatus(c, banner.ActionPublish, "admin", "approver")
+}
+
+func (h *Handler) archiveBanner(c *gin.Context) {
+	h.changeBannerStatus(c, banner.ActionArchive, "admin", "approver")
+}
+
+// changeBannerStatus takes a workflow action on the banner if the user has
+// one of the roles allowed to take it.
+func (h *Handler) changeBannerStatus(c *gin.Context, action string, roles ...string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid id param")
+		return
+	}
+
+	role, err := getUserRole(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !hasRole(role, roles...) {
+		newErrorResponse(c, http.StatusForbidden, "only "+strings.Join(roles, " or ")+" can "+action+" banner")
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		return
+	}
+
+	if err = h.services.ChangeBannerStatus(id, action, getTime(), actor); err != nil {
+		newServiceErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{})
+}
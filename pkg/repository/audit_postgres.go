@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"banner"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+type AuditPostgres struct {
+	db *sqlx.DB
+}
+
+func NewAuditPostgres(db *sqlx.DB) *AuditPostgres {
+	return &AuditPostgres{db: db}
+}
+
+func (r *AuditPostgres) GetAuditEntries(filter banner.AuditFilter) ([]banner.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.ActorId != nil {
+		args = append(args, *filter.ActorId)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+
+	if filter.BannerId != nil {
+		args = append(args, *filter.BannerId)
+		conditions = append(conditions, fmt.Sprintf("banner_id = $%d", len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := fmt.Sprintf("SELECT id, actor_id, action, banner_id, diff, request_id, created_at FROM %s", auditTable)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	entries := make([]banner.AuditEntry, 0)
+	err := r.db.Select(&entries, query, append(args, filter.Limit, filter.Offset)...)
+	return entries, err
+}
+
+// insertAuditEntry records the mutation in the transaction making it, so the
+// audit log never misses a committed change nor lists a rolled back one.
+func insertAuditEntry(tx *sqlx.Tx, actor banner.Actor, action string, bannerId int,
+	before, after *banner.Banner) error {
+	entry, err := banner.NewAuditEntry(actor, action, bannerId, before, after)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (actor_id, action, banner_id, diff, request_id) 
+				VALUES ($1, $2, $3, $4, $5)`, auditTable)
+	_, err = tx.Exec(query, entry.ActorId, entry.Action, entry.BannerId, entry.Diff, entry.RequestId)
+	return err
+}
@@ -3,12 +3,12 @@ package repository
 import (
 	"banner"
 	"database/sql"
-	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type BannerPostgres struct {
@@ -19,97 +19,360 @@ func NewBannerPostgres(db *sqlx.DB) *BannerPostgres {
 	return &BannerPostgres{db: db}
 }
 
-func (r *BannerPostgres) CheckBanner(tagIds []int, featureId int) (bool, error) {
-	checkQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE tag_ids = $1 AND feature_id = $2)", bannersTable)
+func (r *BannerPostgres) CheckBanner(tagIds []int, featureId, priority, excludeId int) ([]int, error) {
+	checkQuery := fmt.Sprintf(`SELECT DISTINCT banner_id FROM %s 
+				WHERE tag_id = ANY($1) AND feature_id = $2 AND priority = $3 AND banner_id <> $4 ORDER BY banner_id`,
+		bannerTagsTable)
 
-	var exists bool
-	err := r.db.QueryRow(checkQuery, pq.Array(tagIds), featureId).Scan(&exists)
+	var bannerIds []int
+	err := r.db.Select(&bannerIds, checkQuery, pq.Array(tagIds), featureId, priority, excludeId)
+	return bannerIds, err
+}
+
+func (r *BannerPostgres) CreateBanner(b banner.Banner, actor banner.Actor) (int, error) {
+	tx, err := r.db.Beginx()
 	if err != nil {
-		return exists, err
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	if exists {
-		return exists, errors.New("banner with specified tag_ids and feature_id already exists")
+	id, err := createBanner(tx, b, actor)
+	if err != nil {
+		return 0, err
 	}
 
-	return exists, nil
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-func (r *BannerPostgres) CreateBanner(banner banner.Banner) (int, error) {
+// ImportBanners creates all the banners or none of them.
+func (r *BannerPostgres) ImportBanners(banners []banner.Banner, actor banner.Actor) ([]int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, 0, len(banners))
+	for _, b := range banners {
+		id, err := createBanner(tx, b, actor)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func createBanner(tx *sqlx.Tx, b banner.Banner, actor banner.Actor) (int, error) {
 	var id int
-	query := fmt.Sprintf(`INSERT INTO %s (tag_ids, feature_id, title, text, url, is_active, created_at, updated_at) 
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`, bannersTable)
-	row := r.db.QueryRow(query,
-		pq.Array(banner.TagIds), banner.FeatureId, banner.Content.Title, banner.Content.Text, banner.Content.Url, banner.IsActive,
-		banner.CreatedAt, banner.UpdatedAt)
+	query := fmt.Sprintf(`INSERT INTO %s (feature_id, content, localized_content, targeting, is_active, 
+				starts_at, ends_at, max_impressions_per_user, impression_period, rollout_percent, priority, status, 
+				created_at, updated_at) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`, bannersTable)
+	row := tx.QueryRow(query,
+		b.FeatureId, b.Content, b.LocalizedContent, b.Targeting, b.IsActive, b.StartsAt, b.EndsAt,
+		b.MaxImpressionsPerUser, b.ImpressionPeriod, b.RolloutPercent, b.Priority, b.Status, b.CreatedAt, b.UpdatedAt)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
+
+	if err := insertBannerTags(tx, id, b.TagIds, b.FeatureId, b.Priority); err != nil {
+		return 0, err
+	}
+
+	if err := insertVariants(tx, id, b.Variants); err != nil {
+		return 0, err
+	}
+
+	if err := insertRevision(tx, id, 1, b, actor.UserId); err != nil {
+		return 0, err
+	}
+
+	if err := auditChange(tx, actor, banner.AuditCreate, id, nil); err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
 func (r *BannerPostgres) GetBannerById(id int) (banner.Banner, error) {
-	tx, err := r.db.Begin()
+	query := fmt.Sprintf("SELECT %s FROM %s b WHERE id = $1 AND deleted_at IS NULL", bannerColumns, bannersTable)
+	return scanBanner(r.db.QueryRow(query, id), id)
+}
+
+// GetDeletedBannerById returns a soft deleted banner with the tags of its
+// current revision, which it gets back on restore.
+func (r *BannerPostgres) GetDeletedBannerById(id int) (banner.Banner, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s b WHERE id = $1 AND deleted_at IS NOT NULL", bannerColumns, bannersTable)
+	b, err := scanBanner(r.db.QueryRow(query, id), id)
 	if err != nil {
-		return banner.Banner{}, err
+		return b, err
+	}
+
+	var tagIDs []byte
+	tagsQuery := fmt.Sprintf(`SELECT r.tag_ids FROM %s r JOIN %s b ON b.id = r.banner_id AND b.version = r.version 
+				WHERE b.id = $1`, revisionsTable, bannersTable)
+	if err = r.db.QueryRow(tagsQuery, id).Scan(&tagIDs); err != nil {
+		return b, err
+	}
+
+	b.TagIds, err = parseTagIds(tagIDs)
+	return b, err
+}
+
+func (r *BannerPostgres) UpdateBannerById(id int, b banner.Banner, actor banner.Actor) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
 	}
 	defer tx.Rollback()
 
-	var content banner.Content
-	var banner banner.Banner
-	var tagIDs []uint8
+	before, err := lockBannerVersion(tx, id, b.RowVersion)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	versionQuery := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) + 1 FROM %s WHERE banner_id = $1", revisionsTable)
+	if err = tx.QueryRow(versionQuery, id).Scan(&version); err != nil {
+		return err
+	}
 
-	queryBanner := fmt.Sprintf("SELECT tag_ids, feature_id, is_active FROM %s WHERE id = $1", bannersTable)
-	if err = r.db.QueryRow(queryBanner, id).Scan(&tagIDs, &banner.FeatureId, &banner.IsActive); err != nil {
-		return banner, err
+	if err = deleteBannerTags(tx, id); err != nil {
+		return err
 	}
 
-	tagID := uint(0)
-	for _, b := range tagIDs {
-		tagID = tagID*10 + uint(b-'0')
+	query := fmt.Sprintf(`
+		UPDATE %s 
+		SET 
+			feature_id = $1, 
+			content = $2, 
+			localized_content = $3,
+			targeting = $4,
+			is_active = $5, 
+			starts_at = $6,
+			ends_at = $7,
+			max_impressions_per_user = $8,
+			impression_period = $9,
+			rollout_percent = $10,
+			priority = $11,
+			status = $12,
+			updated_at = $13,
+			version = $14,
+			row_version = row_version + 1
+		WHERE 
+			id = $15
+	`, bannersTable)
+	_, err = tx.Exec(query, b.FeatureId, b.Content, b.LocalizedContent, b.Targeting, b.IsActive, b.StartsAt,
+		b.EndsAt, b.MaxImpressionsPerUser, b.ImpressionPeriod, b.RolloutPercent, b.Priority, b.Status, b.UpdatedAt,
+		version, id)
+	if err != nil {
+		return err
 	}
 
-	queryContent := fmt.Sprintf("SELECT title, text, url FROM %s WHERE id = $1", bannersTable)
-	if err = r.db.QueryRowx(queryContent, id).StructScan(&content); err != nil {
-		return banner, err
+	if err = insertBannerTags(tx, id, b.TagIds, b.FeatureId, b.Priority); err != nil {
+		return err
 	}
 
-	banner.Content = content
+	if b.Variants != nil {
+		deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE banner_id = $1", variantsTable)
+		if _, err = tx.Exec(deleteQuery, id); err != nil {
+			return err
+		}
 
-	if err = tx.Commit(); err != nil {
-		return banner, err
+		if err = insertVariants(tx, id, b.Variants); err != nil {
+			return err
+		}
+	}
+
+	if err = insertRevision(tx, id, version, b, actor.UserId); err != nil {
+		return err
+	}
+
+	if err = auditChange(tx, actor, banner.AuditUpdate, id, &before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *BannerPostgres) GetBannerRevisions(id int) ([]banner.Revision, error) {
+	query := fmt.Sprintf(`
+		SELECT r.version, r.tag_ids, r.feature_id, r.content, r.localized_content, r.targeting, r.is_active,
+			r.starts_at, r.ends_at, r.max_impressions_per_user, r.impression_period, r.rollout_percent, r.priority,
+			COALESCE(r.author_id, 0), r.created_at, r.version = b.version
+		FROM %s r
+		JOIN %s b ON b.id = r.banner_id
+		WHERE r.banner_id = $1
+		ORDER BY r.version DESC`,
+		revisionsTable, bannersTable)
+
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []banner.Revision
+	for rows.Next() {
+		rev := banner.Revision{BannerId: id}
+		var tagIDs []byte
+		err = rows.Scan(&rev.Version, &tagIDs, &rev.FeatureId, &rev.Content, &rev.LocalizedContent, &rev.Targeting,
+			&rev.IsActive, &rev.StartsAt, &rev.EndsAt, &rev.MaxImpressionsPerUser, &rev.ImpressionPeriod,
+			&rev.RolloutPercent, &rev.Priority, &rev.AuthorId, &rev.CreatedAt, &rev.Current)
+		if err != nil {
+			return nil, err
+		}
+
+		if rev.TagIds, err = parseTagIds(tagIDs); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, sql.ErrNoRows
 	}
 
-	return banner, nil
+	return revisions, nil
 }
 
-func (r *BannerPostgres) UpdateBannerById(id int, banner banner.Banner) error {
+func (r *BannerPostgres) GetBannerRevision(id, version int) (banner.Revision, error) {
+	rev := banner.Revision{BannerId: id, Version: version}
+	var tagIDs []byte
 
 	query := fmt.Sprintf(`
-		UPDATE %s 
-		SET 
-			tag_ids = $1, 
-			feature_id = $2, 
-			title = $3, 
-			text = $4, 
-			url = $5, 
-			is_active = $6, 
-			updated_at = $7
+		SELECT r.tag_ids, r.feature_id, r.content, r.localized_content, r.targeting, r.is_active, r.starts_at,
+			r.ends_at, r.max_impressions_per_user, r.impression_period, r.rollout_percent, r.priority, COALESCE(r.author_id, 0),
+			r.created_at, r.version = b.version
+		FROM %s r
+		JOIN %s b ON b.id = r.banner_id
+		WHERE r.banner_id = $1 AND r.version = $2`,
+		revisionsTable, bannersTable)
+	err := r.db.QueryRow(query, id, version).Scan(&tagIDs, &rev.FeatureId, &rev.Content, &rev.LocalizedContent,
+		&rev.Targeting, &rev.IsActive, &rev.StartsAt, &rev.EndsAt, &rev.MaxImpressionsPerUser, &rev.ImpressionPeriod,
+		&rev.RolloutPercent, &rev.Priority, &rev.AuthorId, &rev.CreatedAt, &rev.Current)
+	if err != nil {
+		return rev, err
+	}
+
+	rev.TagIds, err = parseTagIds(tagIDs)
+	return rev, err
+}
+
+// ActivateBannerRevision rolls the banner back to the revision's state and
+// records the rollback as a new revision by the author.
+func (r *BannerPostgres) ActivateBannerRevision(id, version int, updatedAt string, actor banner.Actor) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getBanner(tx, id, true)
+	if err != nil {
+		return err
+	}
+
+	var newVersion int
+	versionQuery := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) + 1 FROM %s WHERE banner_id = $1", revisionsTable)
+	if err = tx.QueryRow(versionQuery, id).Scan(&newVersion); err != nil {
+		return err
+	}
+
+	if err = deleteBannerTags(tx, id); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s b
+		SET
+			feature_id = r.feature_id,
+			content = r.content,
+			localized_content = r.localized_content,
+			targeting = r.targeting,
+			is_active = r.is_active,
+			starts_at = r.starts_at,
+			ends_at = r.ends_at,
+			max_impressions_per_user = r.max_impressions_per_user,
+			impression_period = r.impression_period,
+			rollout_percent = r.rollout_percent,
+			priority = r.priority,
+			status = $5,
+			version = $4,
+			row_version = b.row_version + 1,
+			updated_at = $3
+		FROM %s r
 		WHERE 
-			id = $8
-	`, bannersTable)
-	_, err := r.db.Exec(query, pq.Array(banner.TagIds), banner.FeatureId, banner.Content.Title, banner.Content.Text,
-		banner.Content.Url, banner.IsActive, banner.UpdatedAt, id)
+			b.id = r.banner_id AND r.banner_id = $1 AND r.version = $2 AND b.deleted_at IS NULL
+		RETURNING r.tag_ids, r.feature_id, r.priority
+	`, bannersTable, revisionsTable)
+
+	var tagIDs []byte
+	var featureId, priority int
+	// The rolled back content has to be reviewed again before it is served.
+	err = tx.QueryRow(query, id, version, updatedAt, newVersion, banner.StatusDraft).Scan(&tagIDs, &featureId,
+		&priority)
+	if err != nil {
+		return err
+	}
 
+	tagIds, err := parseTagIds(tagIDs)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	if err = insertBannerTags(tx, id, tagIds, featureId, priority); err != nil {
+		return err
+	}
+
+	revisionQuery := fmt.Sprintf(`INSERT INTO %s (banner_id, version, tag_ids, feature_id, content, localized_content, 
+				targeting, is_active, starts_at, ends_at, max_impressions_per_user, impression_period, rollout_percent, 
+				priority, author_id, created_at) 
+				SELECT banner_id, $3, tag_ids, feature_id, content, localized_content, targeting, is_active, 
+					starts_at, ends_at, max_impressions_per_user, impression_period, rollout_percent, priority, $4, $5 
+				FROM %s WHERE banner_id = $1 AND version = $2`, revisionsTable, revisionsTable)
+	if _, err = tx.Exec(revisionQuery, id, version, newVersion, actor.UserId, updatedAt); err != nil {
+		return err
+	}
+
+	if err = auditChange(tx, actor, banner.AuditActivate, id, &before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *BannerPostgres) DeleteBannerById(id int) error {
-	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE id = $1", bannersTable)
-	result, err := r.db.Exec(deleteQuery, id)
+// UpdateBannerStatus moves the banner from one workflow status to another,
+// auditing it as the action. It returns sql.ErrNoRows when the banner is no
+// longer in the from status.
+func (r *BannerPostgres) UpdateBannerStatus(id int, action, from, to, updatedAt string, actor banner.Actor) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getBanner(tx, id, true)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET status = $3, updated_at = $4, row_version = row_version + 1 
+				WHERE id = $1 AND status = $2 AND deleted_at IS NULL`, bannersTable)
+	result, err := tx.Exec(query, id, from, to, updatedAt)
 	if err != nil {
 		return err
 	}
@@ -123,87 +386,578 @@ func (r *BannerPostgres) DeleteBannerById(id int) error {
 		return sql.ErrNoRows
 	}
 
-	return nil
+	if err = auditChange(tx, actor, action, id, &before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteBannerById soft deletes the banner. Its tags are dropped so the
+// placement can be reused; the current revision keeps them for a restore.
+func (r *BannerPostgres) DeleteBannerById(id, rowVersion int, actor banner.Actor) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockBannerVersion(tx, id, rowVersion)
+	if err != nil {
+		return err
+	}
+
+	deleteQuery := fmt.Sprintf("UPDATE %s SET deleted_at = now(), row_version = row_version + 1 WHERE id = $1",
+		bannersTable)
+	if _, err = tx.Exec(deleteQuery, id); err != nil {
+		return err
+	}
+
+	if err = deleteBannerTags(tx, id); err != nil {
+		return err
+	}
+
+	if err = insertAuditEntry(tx, actor, banner.AuditDelete, id, &before, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RestoreBannerById undoes a soft delete and puts back the tags of the
+// current revision.
+func (r *BannerPostgres) RestoreBannerById(id int, updatedAt string, actor banner.Actor) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		UPDATE %s b
+		SET deleted_at = NULL, updated_at = $2, row_version = b.row_version + 1
+		FROM %s r
+		WHERE b.id = $1 AND b.deleted_at IS NOT NULL AND r.banner_id = b.id AND r.version = b.version
+		RETURNING r.tag_ids, b.feature_id, b.priority
+	`, bannersTable, revisionsTable)
+
+	var tagIDs []byte
+	var featureId, priority int
+	if err = tx.QueryRow(query, id, updatedAt).Scan(&tagIDs, &featureId, &priority); err != nil {
+		return err
+	}
+
+	tagIds, err := parseTagIds(tagIDs)
+	if err != nil {
+		return err
+	}
+
+	if err = insertBannerTags(tx, id, tagIds, featureId, priority); err != nil {
+		return err
+	}
+
+	if err = auditChange(tx, actor, banner.AuditRestore, id, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedBanners hard deletes banners soft deleted before deletedBefore
+// and returns their ids.
+func (r *BannerPostgres) PurgeDeletedBanners(deletedBefore time.Time) ([]int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ids []int
+	selectQuery := fmt.Sprintf("SELECT id FROM %s WHERE deleted_at < $1 ORDER BY id FOR UPDATE", bannersTable)
+	if err = tx.Select(&ids, selectQuery, deletedBefore); err != nil {
+		return nil, err
+	}
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE id = ANY($1)", bannersTable)
+	if err = deleteAudited(tx, ids, deleteQuery, banner.Actor{}, banner.AuditPurge); err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit()
+}
+
+func (r *BannerPostgres) CountBanners(input banner.BulkDeleteInput) (int, error) {
+	condition, args := bulkDeleteCondition(input)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", bannersTable, condition)
+
+	var count int
+	err := r.db.Get(&count, query, args...)
+	return count, err
 }
 
-func (r *BannerPostgres) GetUserBanner(input banner.UserBannerInput, role string) (banner.Content, error) {
-	var content banner.Content
+// DeleteBannersBatch soft deletes up to limit matching banners and returns
+// their ids.
+func (r *BannerPostgres) DeleteBannersBatch(input banner.BulkDeleteInput, limit int,
+	actor banner.Actor) ([]int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	condition, args := bulkDeleteCondition(input)
+	selectQuery := fmt.Sprintf("SELECT id FROM %s WHERE %s ORDER BY id LIMIT $%d FOR UPDATE",
+		bannersTable, condition, len(args)+1)
 
-	if role == "admin" {
-		adminQuery := fmt.Sprintf("SELECT title, text, url FROM %s WHERE $1 = ANY(tag_ids) AND feature_id = $2", bannersTable)
-		err := r.db.QueryRowx(adminQuery, input.TagId, input.FeatureId).StructScan(&content)
+	var ids []int
+	if err = tx.Select(&ids, selectQuery, append(args, limit)...); err != nil {
+		return nil, err
+	}
+
+	deleteQuery := fmt.Sprintf("UPDATE %s SET deleted_at = now(), row_version = row_version + 1 WHERE id = ANY($1)",
+		bannersTable)
+	if err = deleteAudited(tx, ids, deleteQuery, actor, banner.AuditBulkDelete); err != nil {
+		return nil, err
+	}
+
+	tagsQuery := fmt.Sprintf("DELETE FROM %s WHERE banner_id = ANY($1)", bannerTagsTable)
+	if _, err = tx.Exec(tagsQuery, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit()
+}
+
+// deleteAudited runs the delete query for the locked banners and audits each
+// of them with its state before the delete.
+func deleteAudited(tx *sqlx.Tx, ids []int, deleteQuery string, actor banner.Actor, action string) error {
+	before := make([]banner.Banner, 0, len(ids))
+	for _, id := range ids {
+		b, err := getBanner(tx, id, false)
 		if err != nil {
-			return content, err
+			return err
 		}
-		return content, nil
-	} else {
-		userQuery := fmt.Sprintf(`SELECT title, text, url FROM %s WHERE ($1 = ANY(tag_ids) AND feature_id = $2)
-                                AND is_active = true`, bannersTable)
-		err := r.db.QueryRowx(userQuery, input.TagId, input.FeatureId).StructScan(&content)
-		if err != nil {
-			return content, err
+		before = append(before, b)
+	}
+
+	if _, err := tx.Exec(deleteQuery, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		if err := insertAuditEntry(tx, actor, action, id, &before[i], nil); err != nil {
+			return err
 		}
-		return content, nil
 	}
 
+	return nil
 }
 
-func (r *BannerPostgres) GetAllBanners(input banner.FilterInput) ([]banner.Banner, error) {
-	tx, err := r.db.Begin()
+// GetUserBanners returns every banner that matches the placement and is
+// visible for the role, highest priority and most recently updated first.
+func (r *BannerPostgres) GetUserBanners(input banner.UserBannerInput, role string) ([]banner.Banner, error) {
+	query := fmt.Sprintf(`SELECT b.id, content, localized_content, targeting, is_active, starts_at, ends_at, 
+				max_impressions_per_user, impression_period, rollout_percent, b.priority, b.status 
+				FROM %s b JOIN %s bt ON bt.banner_id = b.id 
+				WHERE bt.tag_id = $1 AND bt.feature_id = $2 AND b.deleted_at IS NULL`,
+		bannersTable, bannerTagsTable)
+	order := " ORDER BY b.priority DESC, b.updated_at DESC, b.id"
+
+	if role != "admin" {
+		query += fmt.Sprintf(" AND b.status = '%s' AND is_active = true AND ", banner.StatusPublished) +
+			scheduleCondition(banner.StateLive)
+	}
+	query += order
+
+	rows, err := r.db.Query(query, input.TagId, input.FeatureId)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
+	defer rows.Close()
 
 	var banners []banner.Banner
+	for rows.Next() {
+		b := banner.Banner{FeatureId: input.FeatureId}
+		err = rows.Scan(&b.Id, &b.Content, &b.LocalizedContent, &b.Targeting, &b.IsActive, &b.StartsAt, &b.EndsAt,
+			&b.MaxImpressionsPerUser, &b.ImpressionPeriod, &b.RolloutPercent, &b.Priority, &b.Status)
+		if err != nil {
+			return nil, err
+		}
+		banners = append(banners, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range banners {
+		if banners[i].Variants, err = getVariants(r.db, banners[i].Id); err != nil {
+			return nil, err
+		}
+	}
+
+	return banners, nil
+}
+
+func (r *BannerPostgres) AssignVariant(variantId, userId int) error {
+	query := fmt.Sprintf("INSERT INTO %s (variant_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		variantAssignmentsTable)
+	_, err := r.db.Exec(query, variantId, userId)
+	return err
+}
+
+func (r *BannerPostgres) GetVariantReport(id int) ([]banner.VariantReport, error) {
+	var exists bool
+	existsQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND deleted_at IS NULL)", bannersTable)
+	if err := r.db.QueryRow(existsQuery, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
 
+	report := make([]banner.VariantReport, 0)
 	query := fmt.Sprintf(`
-        SELECT tag_ids, feature_id, is_active, created_at, updated_at, title, text, url
-        FROM %s 
-        WHERE $1 = ANY(tag_ids) OR feature_id = $2
-        LIMIT $3 OFFSET $4`,
-		bannersTable)
+		SELECT v.id AS variant_id, v.weight, COUNT(a.user_id) AS users
+		FROM %s v
+		LEFT JOIN %s a ON a.variant_id = v.id
+		WHERE v.banner_id = $1
+		GROUP BY v.id
+		ORDER BY v.id`,
+		variantsTable, variantAssignmentsTable)
+	err := r.db.Select(&report, query, id)
+	return report, err
+}
+
+func (r *BannerPostgres) GetBannerVariants(id int) ([]banner.Variant, error) {
+	return getVariants(r.db, id)
+}
 
-	rows, err := tx.Query(query, input.TagId, input.FeatureId, input.Limit, input.Offset)
+func getVariants(q sqlx.Queryer, id int) ([]banner.Variant, error) {
+	query := fmt.Sprintf("SELECT id, content, weight FROM %s WHERE banner_id = $1 ORDER BY id", variantsTable)
+	rows, err := q.Query(query, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var variants []banner.Variant
+	for rows.Next() {
+		var v banner.Variant
+		if err = rows.Scan(&v.Id, &v.Content, &v.Weight); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+
+	return variants, rows.Err()
+}
+
+// GetAllBanners returns up to input.Limit banners after the cursor, if any,
+// and the number of banners matching the filter on all pages.
+func (r *BannerPostgres) GetAllBanners(input banner.FilterInput, after *banner.BannerCursor) ([]banner.Banner, int,
+	error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	condition, args := bannerFilterCondition(input)
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT count(*) FROM %s b WHERE %s", bannersTable, condition)
+	if err = tx.Get(&total, countQuery, args...); err != nil {
+		return nil, 0, err
+	}
+
+	column, cast, desc := sortColumn(input.Sort)
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if after != nil {
+		if column == "b.id" {
+			args = append(args, after.Id)
+			condition += fmt.Sprintf(" AND b.id %s $%d", comparison, len(args))
+		} else {
+			args = append(args, after.Value, after.Id)
+			condition += fmt.Sprintf(" AND (%s, b.id) %s ($%d::%s, $%d)", column, comparison, len(args)-1, cast,
+				len(args))
+		}
+	}
+
+	args = append(args, input.Limit)
+	query := fmt.Sprintf(`
+        SELECT b.id, %s, feature_id, is_active, starts_at, ends_at, max_impressions_per_user, impression_period,
+            rollout_percent, priority, status, row_version, created_at, updated_at, content, localized_content,
+            targeting
+        FROM %s b
+        WHERE %s
+        ORDER BY %s %s, b.id %s LIMIT $%d`,
+		tagIdsColumn, bannersTable, condition, column, direction, direction, len(args))
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var banners []banner.Banner
 	for rows.Next() {
 		var b banner.Banner
 		var tagIDs []byte
-		err = rows.Scan(&tagIDs, &b.FeatureId, &b.IsActive, &b.CreatedAt, &b.UpdatedAt,
-			&b.Content.Title, &b.Content.Text, &b.Content.Url)
+		err = rows.Scan(&b.Id, &tagIDs, &b.FeatureId, &b.IsActive, &b.StartsAt, &b.EndsAt, &b.MaxImpressionsPerUser,
+			&b.ImpressionPeriod, &b.RolloutPercent, &b.Priority, &b.Status, &b.RowVersion, &b.CreatedAt, &b.UpdatedAt,
+			&b.Content,
+			&b.LocalizedContent, &b.Targeting)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
-		tagIDsStr := string(tagIDs)
-		tagIDsStr = strings.Trim(tagIDsStr, "{}")
-		tagIDsSplit := strings.Split(tagIDsStr, ",")
-
-		tagIDsInt := make([]int, len(tagIDsSplit))
-		for i, idStr := range tagIDsSplit {
-			id, err := strconv.Atoi(strings.TrimSpace(idStr))
-			if err != nil {
-				return nil, err
-			}
-			tagIDsInt[i] = id
+		if b.TagIds, err = parseTagIds(tagIDs); err != nil {
+			return nil, 0, err
 		}
 
-		b.TagIds = tagIDsInt
-
 		banners = append(banners, b)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return banners, nil
+	return banners, total, nil
+}
+
+func insertRevision(tx *sqlx.Tx, id, version int, banner banner.Banner, authorId int) error {
+	query := fmt.Sprintf(`INSERT INTO %s (banner_id, version, tag_ids, feature_id, content, localized_content, 
+				targeting, is_active, starts_at, ends_at, max_impressions_per_user, impression_period, rollout_percent, 
+				priority, author_id, created_at) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`, revisionsTable)
+	_, err := tx.Exec(query, id, version, pq.Array(banner.TagIds), banner.FeatureId, banner.Content,
+		banner.LocalizedContent, banner.Targeting, banner.IsActive, banner.StartsAt, banner.EndsAt,
+		banner.MaxImpressionsPerUser, banner.ImpressionPeriod, banner.RolloutPercent, banner.Priority, authorId,
+		banner.UpdatedAt)
+	return err
+}
+
+func insertVariants(tx *sqlx.Tx, id int, variants []banner.Variant) error {
+	query := fmt.Sprintf("INSERT INTO %s (banner_id, content, weight) VALUES ($1, $2, $3)", variantsTable)
+	for _, v := range variants {
+		if _, err := tx.Exec(query, id, v.Content, v.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertBannerTags(tx *sqlx.Tx, id int, tagIds []int, featureId, priority int) error {
+	query := fmt.Sprintf(`INSERT INTO %s (banner_id, tag_id, feature_id, priority) 
+				SELECT $1, tag_id, $3, $4 FROM unnest($2::integer[]) AS tag_id`, bannerTagsTable)
+	_, err := tx.Exec(query, id, pq.Array(tagIds), featureId, priority)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return &banner.ConflictError{}
+	}
+	return err
+}
+
+// lockBannerVersion locks the banner row for the rest of the transaction,
+// makes sure it is still at the row version the caller read and returns it.
+func lockBannerVersion(tx *sqlx.Tx, id, rowVersion int) (banner.Banner, error) {
+	var current int
+	query := fmt.Sprintf("SELECT row_version FROM %s WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", bannersTable)
+	if err := tx.QueryRow(query, id).Scan(&current); err != nil {
+		return banner.Banner{}, err
+	}
+
+	if current != rowVersion {
+		return banner.Banner{}, banner.ErrVersionMismatch
+	}
+
+	return getBanner(tx, id, false)
+}
+
+// getBanner reads the banner with its variants in the transaction, whether it
+// is soft deleted or not. forUpdate locks the row until the transaction ends.
+func getBanner(tx *sqlx.Tx, id int, forUpdate bool) (banner.Banner, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s b WHERE id = $1", bannerColumns, bannersTable)
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	b, err := scanBanner(tx.QueryRow(query, id), id)
+	if err != nil {
+		return b, err
+	}
+
+	b.Variants, err = getVariants(tx, id)
+	return b, err
+}
+
+func scanBanner(row *sql.Row, id int) (banner.Banner, error) {
+	b := banner.Banner{Id: id}
+	var tagIDs []byte
+
+	err := row.Scan(&tagIDs, &b.FeatureId, &b.Content, &b.LocalizedContent, &b.Targeting, &b.IsActive, &b.StartsAt,
+		&b.EndsAt, &b.MaxImpressionsPerUser, &b.ImpressionPeriod, &b.RolloutPercent, &b.Priority, &b.Status,
+		&b.RowVersion, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return b, err
+	}
+
+	b.TagIds, err = parseTagIds(tagIDs)
+	return b, err
+}
+
+// auditChange audits the banner's change from the before state to the one
+// the transaction has written so far.
+func auditChange(tx *sqlx.Tx, actor banner.Actor, action string, id int, before *banner.Banner) error {
+	after, err := getBanner(tx, id, false)
+	if err != nil {
+		return err
+	}
+
+	return insertAuditEntry(tx, actor, action, id, before, &after)
+}
+
+func deleteBannerTags(tx *sqlx.Tx, id int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE banner_id = $1", bannerTagsTable)
+	_, err := tx.Exec(query, id)
+	return err
+}
+
+func parseTagIds(tagIDs []byte) ([]int, error) {
+	tagIDsStr := string(tagIDs)
+	tagIDsStr = strings.Trim(tagIDsStr, "{}")
+	if tagIDsStr == "" {
+		return []int{}, nil
+	}
+	tagIDsSplit := strings.Split(tagIDsStr, ",")
+
+	tagIDsInt := make([]int, len(tagIDsSplit))
+	for i, idStr := range tagIDsSplit {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			return nil, err
+		}
+		tagIDsInt[i] = id
+	}
+
+	return tagIDsInt, nil
+}
+
+func scheduleCondition(state string) string {
+	switch state {
+	case banner.StateScheduled:
+		return "starts_at > now()"
+	case banner.StateExpired:
+		return "ends_at <= now()"
+	default:
+		return "(starts_at IS NULL OR starts_at <= now()) AND (ends_at IS NULL OR ends_at > now())"
+	}
+}
+
+// bannerFilterCondition builds the WHERE clause of GetAllBanners over the
+// banners table aliased b. Banner timestamps are stored in UTC.
+func bannerFilterCondition(input banner.FilterInput) (string, []interface{}) {
+	conditions := []string{"b.deleted_at IS NULL"}
+	var args []interface{}
+
+	if input.TagId != 0 {
+		args = append(args, input.TagId)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE banner_id = b.id AND tag_id = $%d)",
+			bannerTagsTable, len(args)))
+	}
+
+	if input.FeatureId != 0 {
+		args = append(args, input.FeatureId)
+		conditions = append(conditions, fmt.Sprintf("b.feature_id = $%d", len(args)))
+	}
+
+	if input.IsActive != nil {
+		args = append(args, *input.IsActive)
+		conditions = append(conditions, fmt.Sprintf("b.is_active = $%d", len(args)))
+	}
+
+	if input.State != "" {
+		conditions = append(conditions, scheduleCondition(input.State))
+	}
+
+	if input.Status != "" {
+		args = append(args, input.Status)
+		conditions = append(conditions, fmt.Sprintf("b.status = $%d", len(args)))
+	}
+
+	if input.CreatedFrom != nil {
+		args = append(args, input.CreatedFrom.UTC())
+		conditions = append(conditions, fmt.Sprintf("b.created_at >= $%d", len(args)))
+	}
+
+	if input.CreatedTo != nil {
+		args = append(args, input.CreatedTo.UTC())
+		conditions = append(conditions, fmt.Sprintf("b.created_at < $%d", len(args)))
+	}
+
+	if input.UpdatedFrom != nil {
+		args = append(args, input.UpdatedFrom.UTC())
+		conditions = append(conditions, fmt.Sprintf("b.updated_at >= $%d", len(args)))
+	}
+
+	if input.UpdatedTo != nil {
+		args = append(args, input.UpdatedTo.UTC())
+		conditions = append(conditions, fmt.Sprintf("b.updated_at < $%d", len(args)))
+	}
+
+	if input.Search != "" {
+		args = append(args, input.Search)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM (
+					SELECT value FROM jsonb_each_text(b.content)
+					UNION ALL
+					SELECT c.value FROM jsonb_each(b.localized_content) l, jsonb_each_text(l.value) c
+				) v WHERE strpos(lower(v.value), lower($%d)) > 0)`, len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// sortColumn returns the column to sort banners by, the type of its values in
+// cursors and whether the order is descending.
+func sortColumn(sort string) (string, string, bool) {
+	desc := strings.HasPrefix(sort, "-")
+
+	switch strings.TrimPrefix(sort, "-") {
+	case banner.SortByCreatedAt:
+		return "b.created_at", "timestamp", desc
+	case banner.SortByUpdatedAt:
+		return "b.updated_at", "timestamp", desc
+	case banner.SortByPriority:
+		return "b.priority", "integer", desc
+	default:
+		return "b.id", "integer", desc
+	}
+}
+
+func bulkDeleteCondition(input banner.BulkDeleteInput) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	if input.TagId != nil {
+		args = append(args, *input.TagId)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE banner_id = %s.id AND tag_id = $%d)",
+			bannerTagsTable, bannersTable, len(args)))
+	}
+
+	if input.FeatureId != nil {
+		args = append(args, *input.FeatureId)
+		conditions = append(conditions, fmt.Sprintf("feature_id = $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"banner"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+type EventPostgres struct {
+	db *sqlx.DB
+}
+
+func NewEventPostgres(db *sqlx.DB) *EventPostgres {
+	return &EventPostgres{db: db}
+}
+
+func (r *EventPostgres) InsertEvents(events []banner.Event) error {
+	bannerIds := make([]int64, len(events))
+	variantIds := make([]int64, len(events))
+	userIds := make([]int64, len(events))
+	types := make([]string, len(events))
+	createdAt := make([]string, len(events))
+
+	for i, e := range events {
+		bannerIds[i] = int64(e.BannerId)
+		variantIds[i] = int64(e.VariantId)
+		userIds[i] = int64(e.UserId)
+		types[i] = e.Type
+		createdAt[i] = e.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (banner_id, variant_id, user_id, type, created_at)
+		SELECT banner_id, NULLIF(variant_id, 0), user_id, type, created_at
+		FROM unnest($1::integer[], $2::integer[], $3::integer[], $4::varchar[], $5::timestamptz[])
+			AS e(banner_id, variant_id, user_id, type, created_at)`,
+		eventsTable)
+	_, err := r.db.Exec(query, pq.Array(bannerIds), pq.Array(variantIds), pq.Array(userIds),
+		pq.Array(types), pq.Array(createdAt))
+	return err
+}
+
+func (r *EventPostgres) GetDailyStats(bannerId int) ([]banner.DailyStats, error) {
+	stats := make([]banner.DailyStats, 0)
+	query := fmt.Sprintf(`
+		SELECT to_char(date_trunc('day', created_at), 'YYYY-MM-DD') AS day,
+			COUNT(*) FILTER (WHERE type = $2) AS impressions,
+			COUNT(DISTINCT user_id) FILTER (WHERE type = $2) AS unique_users,
+			COUNT(*) FILTER (WHERE type = $3) AS clicks
+		FROM %s
+		WHERE banner_id = $1
+		GROUP BY day
+		ORDER BY day`,
+		eventsTable)
+	err := r.db.Select(&stats, query, bannerId, banner.EventImpression, banner.EventClick)
+	return stats, err
+}
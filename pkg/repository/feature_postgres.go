@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"banner"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+type FeaturePostgres struct {
+	db *sqlx.DB
+}
+
+func NewFeaturePostgres(db *sqlx.DB) *FeaturePostgres {
+	return &FeaturePostgres{db: db}
+}
+
+func (r *FeaturePostgres) CreateFeature(feature banner.Feature) (int, error) {
+	var id int
+	query := fmt.Sprintf(`INSERT INTO %s (name, description, owner, content_schema, created_at) 
+				VALUES ($1, $2, $3, $4, $5) RETURNING id`, featuresTable)
+	row := r.db.QueryRow(query, feature.Name, feature.Description, feature.Owner, feature.ContentSchema,
+		feature.CreatedAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *FeaturePostgres) GetAllFeatures() ([]banner.Feature, error) {
+	var features []banner.Feature
+	query := fmt.Sprintf("SELECT id, name, description, owner, content_schema, created_at FROM %s ORDER BY id", featuresTable)
+	err := r.db.Select(&features, query)
+	return features, err
+}
+
+func (r *FeaturePostgres) GetFeatureById(id int) (banner.Feature, error) {
+	var feature banner.Feature
+	query := fmt.Sprintf("SELECT id, name, description, owner, content_schema, created_at FROM %s WHERE id = $1", featuresTable)
+	err := r.db.Get(&feature, query, id)
+	return feature, err
+}
+
+func (r *FeaturePostgres) UpdateFeature(id int, input banner.UpdateFeatureInput) error {
+	setValues := make([]string, 0)
+	args := make([]interface{}, 0)
+	argId := 1
+
+	if input.Name != nil {
+		setValues = append(setValues, fmt.Sprintf("name = $%d", argId))
+		args = append(args, *input.Name)
+		argId++
+	}
+
+	if input.Description != nil {
+		setValues = append(setValues, fmt.Sprintf("description = $%d", argId))
+		args = append(args, *input.Description)
+		argId++
+	}
+
+	if input.Owner != nil {
+		setValues = append(setValues, fmt.Sprintf("owner = $%d", argId))
+		args = append(args, *input.Owner)
+		argId++
+	}
+
+	if input.ContentSchema != nil {
+		setValues = append(setValues, fmt.Sprintf("content_schema = $%d", argId))
+		args = append(args, input.ContentSchema)
+		argId++
+	}
+
+	if len(setValues) == 0 {
+		_, err := r.GetFeatureById(id)
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", featuresTable, strings.Join(setValues, ", "), argId)
+	args = append(args, id)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *FeaturePostgres) DeleteFeature(id int) error {
+	var used bool
+	usedQuery := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE feature_id = $1) 
+				OR EXISTS (SELECT 1 FROM %s WHERE feature_id = $1)`, bannersTable, templatesTable)
+	if err := r.db.QueryRow(usedQuery, id).Scan(&used); err != nil {
+		return err
+	}
+
+	if used {
+		return banner.ErrInUse
+	}
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE id = $1", featuresTable)
+	result, err := r.db.Exec(deleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
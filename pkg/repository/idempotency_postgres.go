@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"banner"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IdempotencyPostgres struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyPostgres(db *sqlx.DB) *IdempotencyPostgres {
+	return &IdempotencyPostgres{db: db}
+}
+
+// ReserveIdempotencyKey claims the record's key, taking over a key created
+// before expiredBefore. When the key is taken, the stored record is returned
+// instead and claimed is false.
+func (r *IdempotencyPostgres) ReserveIdempotencyKey(record banner.IdempotencyRecord,
+	expiredBefore time.Time) (banner.IdempotencyRecord, bool, error) {
+	insertQuery := fmt.Sprintf(`INSERT INTO %s (user_id, key, fingerprint) VALUES ($1, $2, $3) 
+				ON CONFLICT (user_id, key) DO UPDATE 
+				SET fingerprint = EXCLUDED.fingerprint, status_code = 0, header = '{}', body = NULL, created_at = now()
+				WHERE %s.created_at < $4`, idempotencyTable, idempotencyTable)
+	result, err := r.db.Exec(insertQuery, record.UserId, record.Key, record.Fingerprint, expiredBefore)
+	if err != nil {
+		return record, false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return record, false, err
+	}
+
+	if rowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing banner.IdempotencyRecord
+	var header []byte
+	selectQuery := fmt.Sprintf(`SELECT user_id, key, fingerprint, status_code, header, body FROM %s 
+				WHERE user_id = $1 AND key = $2`, idempotencyTable)
+	err = r.db.QueryRow(selectQuery, record.UserId, record.Key).Scan(&existing.UserId, &existing.Key,
+		&existing.Fingerprint, &existing.StatusCode, &header, &existing.Body)
+	if err != nil {
+		return record, false, err
+	}
+
+	if err = json.Unmarshal(header, &existing.Header); err != nil {
+		return record, false, err
+	}
+
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding the key.
+func (r *IdempotencyPostgres) CompleteIdempotencyKey(record banner.IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET status_code = $3, header = $4, body = $5 
+				WHERE user_id = $1 AND key = $2`, idempotencyTable)
+	_, err = r.db.Exec(query, record.UserId, record.Key, record.StatusCode, header, record.Body)
+	return err
+}
+
+// ReleaseIdempotencyKey frees a key whose request didn't complete so that it
+// can be retried.
+func (r *IdempotencyPostgres) ReleaseIdempotencyKey(userId int, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = $1 AND key = $2 AND status_code = 0", idempotencyTable)
+	_, err := r.db.Exec(query, userId, key)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys drops keys created before expiredBefore and
+// returns how many were dropped.
+func (r *IdempotencyPostgres) DeleteExpiredIdempotencyKeys(expiredBefore time.Time) (int, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE created_at < $1", idempotencyTable)
+	result, err := r.db.Exec(query, expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
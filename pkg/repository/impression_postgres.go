@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type ImpressionPostgres struct {
+	db *sqlx.DB
+}
+
+func NewImpressionPostgres(db *sqlx.DB) *ImpressionPostgres {
+	return &ImpressionPostgres{db: db}
+}
+
+func (r *ImpressionPostgres) GetImpressionCount(bannerId, userId int, windowStart time.Time) (int, error) {
+	var count int
+	query := fmt.Sprintf(`SELECT COALESCE(SUM(count), 0) FROM %s 
+				WHERE banner_id = $1 AND user_id = $2 AND window_start = $3`, impressionCountsTable)
+	err := r.db.Get(&count, query, bannerId, userId, windowStart)
+	return count, err
+}
+
+// CountImpression counts one more impression unless the user already saw the
+// banner max times in the window. It reports whether the impression counted.
+func (r *ImpressionPostgres) CountImpression(bannerId, userId int, windowStart time.Time, max int) (bool, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (banner_id, user_id, window_start, count) SELECT $1, $2, $3, 1 WHERE $4 > 0
+				ON CONFLICT (banner_id, user_id, window_start) DO UPDATE SET count = %s.count + 1 
+				WHERE %s.count < $4 RETURNING count`,
+		impressionCountsTable, impressionCountsTable, impressionCountsTable)
+
+	var count int
+	err := r.db.QueryRow(query, bannerId, userId, windowStart, max).Scan(&count)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"banner"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type JobPostgres struct {
+	db *sqlx.DB
+}
+
+func NewJobPostgres(db *sqlx.DB) *JobPostgres {
+	return &JobPostgres{db: db}
+}
+
+func (r *JobPostgres) CreateJob(job banner.Job) (int, error) {
+	var id int
+	query := fmt.Sprintf("INSERT INTO %s (type, status, tag_id, feature_id) VALUES ($1, $2, $3, $4) RETURNING id", jobsTable)
+	row := r.db.QueryRow(query, job.Type, job.Status, job.TagId, job.FeatureId)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *JobPostgres) GetJobById(id int) (banner.Job, error) {
+	var job banner.Job
+	query := fmt.Sprintf(`SELECT id, type, status, tag_id, feature_id, total, processed, error, created_at, updated_at
+				FROM %s WHERE id = $1`, jobsTable)
+	err := r.db.Get(&job, query, id)
+	return job, err
+}
+
+// UpdateJob records the progress of an unfinished job. It returns
+// sql.ErrNoRows when the job already finished or was failed as stale.
+func (r *JobPostgres) UpdateJob(job banner.Job) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, total = $2, processed = $3, error = $4, updated_at = now()
+				WHERE id = $5 AND status IN ($6, $7)`, jobsTable)
+	result, err := r.db.Exec(query, job.Status, job.Total, job.Processed, job.Error, job.Id,
+		banner.JobPending, banner.JobRunning)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FailStaleJobs marks pending and running jobs that made no progress since
+// staleBefore failed with the message. Jobs run in the instance that created
+// them, so nothing finishes the jobs of an instance that went away.
+func (r *JobPostgres) FailStaleJobs(staleBefore time.Time, message string) (int, error) {
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, error = $2, updated_at = now() 
+				WHERE status IN ($3, $4) AND updated_at < $5`, jobsTable)
+	result, err := r.db.Exec(query, banner.JobFailed, message, banner.JobPending, banner.JobRunning, staleBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	failed, err := result.RowsAffected()
+	return int(failed), err
+}
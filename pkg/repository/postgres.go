@@ -7,10 +7,34 @@ import (
 )
 
 const (
-	usersTable   = "users"
-	bannersTable = "banners"
+	usersTable       = "users"
+	bannersTable     = "banners"
+	bannerTagsTable  = "banner_tags"
+	revisionsTable   = "banner_revisions"
+	variantsTable    = "banner_variants"
+	jobsTable        = "jobs"
+	featuresTable    = "features"
+	tagsTable        = "tags"
+	eventsTable      = "events"
+	auditTable       = "audit_log"
+	idempotencyTable = "idempotency_keys"
+	templatesTable   = "banner_templates"
+
+	impressionCountsTable   = "impression_counts"
+	variantAssignmentsTable = "variant_assignments"
 )
 
+const uniqueViolation = "23505"
+
+// tagIdsColumn collects tag ids of the banner aliased as b into an array
+// shaped like the former tag_ids column.
+var tagIdsColumn = fmt.Sprintf("ARRAY(SELECT tag_id FROM %s WHERE banner_id = b.id ORDER BY tag_id)", bannerTagsTable)
+
+// bannerColumns are the columns scanBanner reads from the banners table aliased b.
+var bannerColumns = tagIdsColumn + `, feature_id, content, localized_content, targeting, is_active, starts_at, 
+				ends_at, max_impressions_per_user, impression_period, rollout_percent, priority, status, row_version, 
+				created_at, updated_at`
+
 type Config struct {
 	Host     string
 	Port     string
@@ -3,6 +3,7 @@ package repository
 import (
 	"banner"
 	"github.com/jmoiron/sqlx"
+	"time"
 )
 
 type Authorization interface {
@@ -13,23 +14,106 @@ type Authorization interface {
 }
 
 type Banner interface {
-	CheckBanner(tagIds []int, featureId int) (bool, error)
-	CreateBanner(banner banner.Banner) (int, error)
+	CheckBanner(tagIds []int, featureId, priority, excludeId int) ([]int, error)
+	CreateBanner(banner banner.Banner, actor banner.Actor) (int, error)
 	GetBannerById(id int) (banner.Banner, error)
-	UpdateBannerById(id int, banner banner.Banner) error
-	DeleteBannerById(id int) error
-	GetUserBanner(input banner.UserBannerInput, role string) (banner.Content, error)
-	GetAllBanners(input banner.FilterInput) ([]banner.Banner, error)
+	GetDeletedBannerById(id int) (banner.Banner, error)
+	ImportBanners(banners []banner.Banner, actor banner.Actor) ([]int, error)
+	UpdateBannerById(id int, banner banner.Banner, actor banner.Actor) error
+	GetBannerRevisions(id int) ([]banner.Revision, error)
+	GetBannerRevision(id, version int) (banner.Revision, error)
+	ActivateBannerRevision(id, version int, updatedAt string, actor banner.Actor) error
+	UpdateBannerStatus(id int, action, from, to, updatedAt string, actor banner.Actor) error
+	DeleteBannerById(id, rowVersion int, actor banner.Actor) error
+	RestoreBannerById(id int, updatedAt string, actor banner.Actor) error
+	PurgeDeletedBanners(deletedBefore time.Time) ([]int, error)
+	CountBanners(input banner.BulkDeleteInput) (int, error)
+	DeleteBannersBatch(input banner.BulkDeleteInput, limit int, actor banner.Actor) ([]int, error)
+	GetUserBanners(input banner.UserBannerInput, role string) ([]banner.Banner, error)
+	GetBannerVariants(id int) ([]banner.Variant, error)
+	AssignVariant(variantId, userId int) error
+	GetVariantReport(id int) ([]banner.VariantReport, error)
+	GetAllBanners(input banner.FilterInput, after *banner.BannerCursor) ([]banner.Banner, int, error)
+}
+
+type BannerTemplate interface {
+	CreateBannerTemplate(template banner.BannerTemplate) (int, error)
+	GetAllBannerTemplates() ([]banner.BannerTemplate, error)
+	GetBannerTemplateById(id int) (banner.BannerTemplate, error)
+	UpdateBannerTemplate(id int, template banner.BannerTemplate) error
+	DeleteBannerTemplate(id int) error
+}
+
+type Job interface {
+	CreateJob(job banner.Job) (int, error)
+	GetJobById(id int) (banner.Job, error)
+	UpdateJob(job banner.Job) error
+	FailStaleJobs(staleBefore time.Time, message string) (int, error)
+}
+
+type Feature interface {
+	CreateFeature(feature banner.Feature) (int, error)
+	GetAllFeatures() ([]banner.Feature, error)
+	GetFeatureById(id int) (banner.Feature, error)
+	UpdateFeature(id int, input banner.UpdateFeatureInput) error
+	DeleteFeature(id int) error
+}
+
+type Tag interface {
+	CreateTag(tag banner.Tag) (int, error)
+	GetAllTags() ([]banner.Tag, error)
+	GetTagById(id int) (banner.Tag, error)
+	GetMissingTagIds(ids []int) ([]int, error)
+	UpdateTag(id int, input banner.UpdateTagInput) error
+	DeleteTag(id int) error
+}
+
+type Event interface {
+	InsertEvents(events []banner.Event) error
+	GetDailyStats(bannerId int) ([]banner.DailyStats, error)
+}
+
+type Impression interface {
+	GetImpressionCount(bannerId, userId int, windowStart time.Time) (int, error)
+	CountImpression(bannerId, userId int, windowStart time.Time, max int) (bool, error)
+}
+
+type Audit interface {
+	GetAuditEntries(filter banner.AuditFilter) ([]banner.AuditEntry, error)
+}
+
+type Idempotency interface {
+	ReserveIdempotencyKey(record banner.IdempotencyRecord, expiredBefore time.Time) (banner.IdempotencyRecord, bool,
+		error)
+	CompleteIdempotencyKey(record banner.IdempotencyRecord) error
+	ReleaseIdempotencyKey(userId int, key string) error
+	DeleteExpiredIdempotencyKeys(expiredBefore time.Time) (int, error)
 }
 
 type Repository struct {
 	Authorization
 	Banner
+	BannerTemplate
+	Job
+	Feature
+	Tag
+	Event
+	Impression
+	Audit
+	Idempotency
 }
 
 func NewRepository(db *sqlx.DB) *Repository {
 	return &Repository{
-		Authorization: NewAuthPostgres(db),
-		Banner:        NewBannerPostgres(db),
+		Authorization:  NewAuthPostgres(db),
+		Banner:         NewBannerPostgres(db),
+		BannerTemplate: NewTemplatePostgres(db),
+		Job:            NewJobPostgres(db),
+		Feature:        NewFeaturePostgres(db),
+		Tag:            NewTagPostgres(db),
+		Event:          NewEventPostgres(db),
+		Impression:     NewImpressionPostgres(db),
+		Audit:          NewAuditPostgres(db),
+		Idempotency:    NewIdempotencyPostgres(db),
 	}
 }
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"banner"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
+)
+
+type TagPostgres struct {
+	db *sqlx.DB
+}
+
+func NewTagPostgres(db *sqlx.DB) *TagPostgres {
+	return &TagPostgres{db: db}
+}
+
+func (r *TagPostgres) CreateTag(tag banner.Tag) (int, error) {
+	var id int
+	query := fmt.Sprintf("INSERT INTO %s (name, description, owner, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		tagsTable)
+	row := r.db.QueryRow(query, tag.Name, tag.Description, tag.Owner, tag.CreatedAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *TagPostgres) GetAllTags() ([]banner.Tag, error) {
+	var tags []banner.Tag
+	query := fmt.Sprintf("SELECT id, name, description, owner, created_at FROM %s ORDER BY id", tagsTable)
+	err := r.db.Select(&tags, query)
+	return tags, err
+}
+
+func (r *TagPostgres) GetTagById(id int) (banner.Tag, error) {
+	var tag banner.Tag
+	query := fmt.Sprintf("SELECT id, name, description, owner, created_at FROM %s WHERE id = $1", tagsTable)
+	err := r.db.Get(&tag, query, id)
+	return tag, err
+}
+
+func (r *TagPostgres) GetMissingTagIds(ids []int) ([]int, error) {
+	var missing []int
+	query := fmt.Sprintf(`SELECT DISTINCT u.id FROM unnest($1::integer[]) AS u(id) 
+				WHERE NOT EXISTS (SELECT 1 FROM %s t WHERE t.id = u.id) ORDER BY u.id`, tagsTable)
+	err := r.db.Select(&missing, query, pq.Array(ids))
+	return missing, err
+}
+
+func (r *TagPostgres) UpdateTag(id int, input banner.UpdateTagInput) error {
+	setValues := make([]string, 0)
+	args := make([]interface{}, 0)
+	argId := 1
+
+	if input.Name != nil {
+		setValues = append(setValues, fmt.Sprintf("name = $%d", argId))
+		args = append(args, *input.Name)
+		argId++
+	}
+
+	if input.Description != nil {
+		setValues = append(setValues, fmt.Sprintf("description = $%d", argId))
+		args = append(args, *input.Description)
+		argId++
+	}
+
+	if input.Owner != nil {
+		setValues = append(setValues, fmt.Sprintf("owner = $%d", argId))
+		args = append(args, *input.Owner)
+		argId++
+	}
+
+	if len(setValues) == 0 {
+		_, err := r.GetTagById(id)
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", tagsTable, strings.Join(setValues, ", "), argId)
+	args = append(args, id)
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *TagPostgres) DeleteTag(id int) error {
+	// Targeting rules of soft deleted banners count too, they may be restored.
+	var used bool
+	usedQuery := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE tag_id = $1) 
+				OR EXISTS (SELECT 1 FROM %s WHERE jsonb_path_exists(targeting, '$.**.tag ? (@ == $tag)', 
+					jsonb_build_object('tag', $1::integer)))`, bannerTagsTable, bannersTable)
+	if err := r.db.QueryRow(usedQuery, id).Scan(&used); err != nil {
+		return err
+	}
+
+	if used {
+		return banner.ErrInUse
+	}
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE id = $1", tagsTable)
+	result, err := r.db.Exec(deleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
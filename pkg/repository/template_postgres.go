@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"banner"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const templateColumns = `id, name, description, feature_id, content, localized_content, targeting, is_active, 
+				max_impressions_per_user, impression_period, rollout_percent, priority, created_at, updated_at`
+
+type TemplatePostgres struct {
+	db *sqlx.DB
+}
+
+func NewTemplatePostgres(db *sqlx.DB) *TemplatePostgres {
+	return &TemplatePostgres{db: db}
+}
+
+func (r *TemplatePostgres) CreateBannerTemplate(template banner.BannerTemplate) (int, error) {
+	var id int
+	query := fmt.Sprintf(`INSERT INTO %s (name, description, feature_id, content, localized_content, targeting, 
+				is_active, max_impressions_per_user, impression_period, rollout_percent, priority, created_at, updated_at) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`, templatesTable)
+	row := r.db.QueryRow(query, template.Name, template.Description, template.FeatureId, template.Content,
+		template.LocalizedContent, template.Targeting, template.IsActive, template.MaxImpressionsPerUser,
+		template.ImpressionPeriod, template.RolloutPercent, template.Priority, template.CreatedAt, template.UpdatedAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, templateError(err)
+	}
+	return id, nil
+}
+
+func (r *TemplatePostgres) GetAllBannerTemplates() ([]banner.BannerTemplate, error) {
+	var templates []banner.BannerTemplate
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY id", templateColumns, templatesTable)
+	err := r.db.Select(&templates, query)
+	return templates, err
+}
+
+func (r *TemplatePostgres) GetBannerTemplateById(id int) (banner.BannerTemplate, error) {
+	var template banner.BannerTemplate
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", templateColumns, templatesTable)
+	err := r.db.Get(&template, query, id)
+	return template, err
+}
+
+func (r *TemplatePostgres) UpdateBannerTemplate(id int, template banner.BannerTemplate) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET
+			name = $1,
+			description = $2,
+			feature_id = $3,
+			content = $4,
+			localized_content = $5,
+			targeting = $6,
+			is_active = $7,
+			max_impressions_per_user = $8,
+			impression_period = $9,
+			rollout_percent = $10,
+			priority = $11,
+			updated_at = $12
+		WHERE
+			id = $13
+	`, templatesTable)
+	result, err := r.db.Exec(query, template.Name, template.Description, template.FeatureId, template.Content,
+		template.LocalizedContent, template.Targeting, template.IsActive, template.MaxImpressionsPerUser,
+		template.ImpressionPeriod, template.RolloutPercent, template.Priority, template.UpdatedAt, id)
+	if err != nil {
+		return templateError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *TemplatePostgres) DeleteBannerTemplate(id int) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", templatesTable)
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func templateError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return banner.ErrNameTaken
+	}
+	return err
+}
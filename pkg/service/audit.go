@@ -0,0 +1,18 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+)
+
+type AuditService struct {
+	repo repository.Audit
+}
+
+func NewAuditService(repo repository.Audit) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+func (s *AuditService) GetAuditEntries(filter banner.AuditFilter) ([]banner.AuditEntry, error) {
+	return s.repo.GetAuditEntries(filter)
+}
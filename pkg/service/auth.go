@@ -19,8 +19,7 @@ type AuthService struct {
 
 type tokenClaims struct {
 	jwt.StandardClaims
-	UserId int    `json:"user_id"`
-	Role   string `json:"role"`
+	banner.Claims
 }
 
 func NewAuthService(repo repository.Authorization) *AuthService {
@@ -49,13 +48,16 @@ func (s *AuthService) GenerateToken(nickname, passwordHash string) (string, erro
 			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
-		user.Id,
-		user.Role,
+		banner.Claims{
+			UserId:   user.Id,
+			Role:     user.Role,
+			Nickname: nickname,
+		},
 	})
 	return token.SignedString([]byte(signingKey))
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, string, error) {
+func (s *AuthService) ParseToken(accessToken string) (banner.Claims, error) {
 	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(accessToken *jwt.Token) (interface{}, error) {
 		if _, ok := accessToken.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
@@ -63,11 +65,11 @@ func (s *AuthService) ParseToken(accessToken string) (int, string, error) {
 		return []byte(signingKey), nil
 	})
 	if err != nil {
-		return 0, "", err
+		return banner.Claims{}, err
 	}
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return 0, "", errors.New("token claims are not of type *tokenClaims")
+		return banner.Claims{}, errors.New("token claims are not of type *tokenClaims")
 	}
-	return claims.UserId, claims.Role, nil
+	return claims.Claims, nil
 }
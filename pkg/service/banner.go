@@ -3,40 +3,556 @@ package service
 import (
 	"banner"
 	"banner/pkg/repository"
+	"database/sql"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"time"
 )
 
 type BannerService struct {
-	repo repository.Banner
+	repo        repository.Banner
+	features    repository.Feature
+	tags        repository.Tag
+	impressions repository.Impression
+	recorder    *EventRecorder
+	fallback    []string
+	supported   []string
 }
 
-func NewBannerService(repo repository.Banner) *BannerService {
-	return &BannerService{repo: repo}
+func NewBannerService(repo repository.Banner, features repository.Feature, tags repository.Tag,
+	impressions repository.Impression, recorder *EventRecorder, fallbackLocales,
+	supportedLocales []string) *BannerService {
+	return &BannerService{repo: repo, features: features, tags: tags, impressions: impressions, recorder: recorder,
+		fallback: fallbackLocales, supported: supportedLocales}
 }
 
-func (s *BannerService) CheckBanner(tagIds []int, featureId int) (bool, error) {
-	return s.repo.CheckBanner(tagIds, featureId)
+func (s *BannerService) CheckBanner(tagIds []int, featureId, priority, excludeId int) ([]int, error) {
+	return s.repo.CheckBanner(tagIds, featureId, priority, excludeId)
 }
 
-func (s *BannerService) CreateBanner(banner banner.Banner) (int, error) {
-	return s.repo.CreateBanner(banner)
+func (s *BannerService) CreateBanner(b banner.Banner, actor banner.Actor) (int, error) {
+	if err := s.checkBanner(&b, 0); err != nil {
+		return 0, err
+	}
+
+	id, err := s.repo.CreateBanner(b, actor)
+	if err != nil {
+		return 0, s.conflictIds(err, b.TagIds, b.FeatureId, b.Priority, 0)
+	}
+
+	return id, nil
+}
+
+// CloneBanner creates a copy of the banner with its variants. The copy has to
+// move to other tags, feature or priority not to conflict with the original.
+func (s *BannerService) CloneBanner(id int, input banner.CloneBannerInput, actor banner.Actor) (int, error) {
+	b, err := s.repo.GetBannerById(id)
+	if err != nil {
+		return 0, err
+	}
+
+	variants, err := s.repo.GetBannerVariants(id)
+	if err != nil {
+		return 0, err
+	}
+	for i := range variants {
+		variants[i].Id = 0
+	}
+	b.Variants = variants
+
+	if len(input.TagIds) > 0 {
+		b.TagIds = input.TagIds
+	}
+	if input.FeatureId != nil {
+		b.FeatureId = *input.FeatureId
+	}
+	if input.Priority != nil {
+		b.Priority = *input.Priority
+	}
+
+	b.Id = 0
+	b.RowVersion = 0
+	b.Status = input.Status
+	b.CreatedAt = input.CreatedAt
+	b.UpdatedAt = input.CreatedAt
+
+	return s.CreateBanner(b, actor)
 }
 
 func (s *BannerService) GetBannerById(id int) (banner.Banner, error) {
 	return s.repo.GetBannerById(id)
 }
 
-func (s *BannerService) UpdateBannerById(id int, banner banner.Banner) error {
-	return s.repo.UpdateBannerById(id, banner)
+func (s *BannerService) UpdateBannerById(id int, b banner.Banner, actor banner.Actor) error {
+	if err := s.checkBanner(&b, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateBannerById(id, b, actor); err != nil {
+		return s.conflictIds(err, b.TagIds, b.FeatureId, b.Priority, id)
+	}
+
+	return nil
+}
+
+func (s *BannerService) GetBannerRevisions(id int) ([]banner.Revision, error) {
+	return s.repo.GetBannerRevisions(id)
+}
+
+func (s *BannerService) ActivateBannerRevision(id, version int, updatedAt string, actor banner.Actor) error {
+	revision, err := s.repo.GetBannerRevision(id, version)
+	if err != nil {
+		return err
+	}
+
+	variants, err := s.repo.GetBannerVariants(id)
+	if err != nil {
+		return err
+	}
+
+	// Tags, the feature and its schema may have changed since the revision was
+	// saved, so it has to pass the checks of a new save.
+	b := banner.Banner{
+		TagIds:                revision.TagIds,
+		FeatureId:             revision.FeatureId,
+		Content:               revision.Content,
+		LocalizedContent:      revision.LocalizedContent,
+		Targeting:             revision.Targeting,
+		IsActive:              revision.IsActive,
+		StartsAt:              revision.StartsAt,
+		EndsAt:                revision.EndsAt,
+		MaxImpressionsPerUser: revision.MaxImpressionsPerUser,
+		ImpressionPeriod:      revision.ImpressionPeriod,
+		RolloutPercent:        revision.RolloutPercent,
+		Priority:              revision.Priority,
+		Variants:              variants,
+	}
+	if err = s.checkBanner(&b, id); err != nil {
+		return err
+	}
+
+	if err = s.repo.ActivateBannerRevision(id, version, updatedAt, actor); err != nil {
+		return s.conflictIds(err, revision.TagIds, revision.FeatureId, revision.Priority, id)
+	}
+
+	return nil
+}
+
+func (s *BannerService) DeleteBannerById(id, rowVersion int, actor banner.Actor) error {
+	return s.repo.DeleteBannerById(id, rowVersion, actor)
+}
+
+// RestoreBannerById puts a soft deleted banner back if its tags still exist
+// and no other banner took its place in the meantime.
+func (s *BannerService) RestoreBannerById(id int, updatedAt string, actor banner.Actor) error {
+	b, err := s.repo.GetDeletedBannerById(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.checkReferences(b); err != nil {
+		return err
+	}
+
+	if err = s.checkConflicts(b.TagIds, b.FeatureId, b.Priority, id); err != nil {
+		return err
+	}
+
+	if err = s.repo.RestoreBannerById(id, updatedAt, actor); err != nil {
+		return s.conflictIds(err, b.TagIds, b.FeatureId, b.Priority, id)
+	}
+
+	return nil
+}
+
+func (s *BannerService) CountBanners(input banner.BulkDeleteInput) (int, error) {
+	return s.repo.CountBanners(input)
+}
+
+func (s *BannerService) DeleteBannersBatch(input banner.BulkDeleteInput, limit int,
+	actor banner.Actor) ([]int, error) {
+	return s.repo.DeleteBannersBatch(input, limit, actor)
+}
+
+func (s *BannerService) GetUserBanner(input banner.UserBannerInput, userId int, role string) (banner.UserBanner, error) {
+	banners, err := s.GetUserBanners(input, 1, userId, role)
+	if err != nil {
+		return banner.UserBanner{}, err
+	}
+
+	if len(banners) == 0 {
+		return banner.UserBanner{}, sql.ErrNoRows
+	}
+
+	return banners[0], nil
+}
+
+// GetUserBanners serves up to limit candidate banners in priority order,
+// skipping those rejectReason rules out for the user.
+func (s *BannerService) GetUserBanners(input banner.UserBannerInput, limit, userId int,
+	role string) ([]banner.UserBanner, error) {
+	candidates, err := s.repo.GetUserBanners(input, role)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	banners := make([]banner.UserBanner, 0, limit)
+	for _, b := range candidates {
+		if len(banners) == limit {
+			break
+		}
+
+		reason, err := s.rejectReason(b, input, userId, role, now)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			continue
+		}
+
+		served, ok, err := s.serveBanner(b, input, userId, role, now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			banners = append(banners, served)
+		}
+	}
+
+	return banners, nil
 }
 
-func (s *BannerService) DeleteBannerById(id int) error {
-	return s.repo.DeleteBannerById(id)
+// rejectReason tells why the banner can't be served to the user, or returns
+// an empty string when it can. Admins are served every candidate.
+func (s *BannerService) rejectReason(b banner.Banner, input banner.UserBannerInput, userId int, role string,
+	now time.Time) (string, error) {
+	if role == "admin" {
+		return "", nil
+	}
+
+	if b.Status != banner.StatusPublished {
+		return banner.RejectUnpublished, nil
+	}
+
+	if !b.IsActive {
+		return banner.RejectInactive, nil
+	}
+
+	if state := b.ScheduleState(now); state != banner.StateLive {
+		return state, nil
+	}
+
+	if b.Targeting != nil && !matchRule(*b.Targeting, input.Attributes, input.TagId) {
+		return banner.RejectTargeting, nil
+	}
+
+	if !inRollout(b, userId) {
+		return banner.RejectRollout, nil
+	}
+
+	return "", nil
+}
+
+// Simulate explains which banner the described user would get without
+// serving it: no variant assignment, impression or event is recorded.
+func (s *BannerService) Simulate(input banner.SimulateInput) (banner.Simulation, error) {
+	userInput := banner.UserBannerInput{
+		TagId:           input.TagId,
+		FeatureId:       input.FeatureId,
+		UseLastRevision: input.UseLastRevision,
+		Attributes:      input.Attributes,
+	}
+
+	// Admin candidates include unpublished, inactive and scheduled banners, so every
+	// rejection can be explained.
+	candidates, err := s.repo.GetUserBanners(userInput, "admin")
+	if err != nil {
+		return banner.Simulation{}, err
+	}
+
+	now := time.Now()
+	simulation := banner.Simulation{Candidates: make([]banner.SimulatedCandidate, 0, len(candidates))}
+	for _, b := range candidates {
+		candidate := banner.SimulatedCandidate{BannerId: b.Id, Priority: b.Priority}
+
+		candidate.RejectedReason, err = s.rejectReason(b, userInput, input.UserId, input.Role, now)
+		if err != nil {
+			return banner.Simulation{}, err
+		}
+
+		if candidate.RejectedReason == "" && input.Role != "admin" {
+			capped, err := s.isCapped(b, input.UserId, now)
+			if err != nil {
+				return banner.Simulation{}, err
+			}
+			if capped {
+				candidate.RejectedReason = banner.RejectCapped
+			}
+		}
+
+		var served banner.UserBanner
+		if candidate.RejectedReason == "" {
+			if served, err = s.resolveContent(b, userInput, input.UserId, input.Role); err != nil {
+				candidate.RejectedReason = banner.RejectRenderFailed
+			}
+		}
+
+		if candidate.RejectedReason == "" && simulation.BannerId == nil {
+			candidate.Selected = true
+			simulation.BannerId = &candidate.BannerId
+			simulation.VariantId = served.VariantId
+		}
+
+		simulation.Candidates = append(simulation.Candidates, candidate)
+	}
+
+	return simulation, nil
+}
+
+// isCapped only reads the impression count, serveBanner counts impressions
+// against the cap atomically.
+func (s *BannerService) isCapped(b banner.Banner, userId int, now time.Time) (bool, error) {
+	if b.MaxImpressionsPerUser == nil {
+		return false, nil
+	}
+
+	count, err := s.impressions.GetImpressionCount(b.Id, userId, capWindowStart(b, now))
+	if err != nil {
+		return false, err
+	}
+
+	return count >= *b.MaxImpressionsPerUser, nil
+}
+
+// resolveContent picks and renders the content shown to the user. Variants
+// are not translated, so a picked variant replaces the localized content.
+func (s *BannerService) resolveContent(b banner.Banner, input banner.UserBannerInput, userId int,
+	role string) (banner.UserBanner, error) {
+	result := banner.UserBanner{BannerId: b.Id}
+	result.Content, result.Locale = localize(b, input.Locales, s.fallback)
+
+	if variant, ok := pickVariant(b.Variants, b.Id, userId); ok {
+		result.Content = variant.Content
+		result.VariantId = variant.Id
+		result.Locale = ""
+	}
+
+	content, err := renderContent(result.Content, templateVars(input, userId, role))
+	if err != nil {
+		return result, err
+	}
+	result.Content = content
+
+	return result, nil
+}
+
+// serveBanner resolves the banner's content and records the impression. It
+// reports false without recording anything for a banner whose template fails
+// to render or whose frequency cap the user has reached.
+func (s *BannerService) serveBanner(b banner.Banner, input banner.UserBannerInput, userId int, role string,
+	now time.Time) (banner.UserBanner, bool, error) {
+	result, err := s.resolveContent(b, input, userId, role)
+	if err != nil {
+		logrus.Errorf("failed to render banner %d: %s", b.Id, err.Error())
+		return result, false, nil
+	}
+
+	if role != "admin" && b.MaxImpressionsPerUser != nil {
+		counted, err := s.impressions.CountImpression(b.Id, userId, capWindowStart(b, now), *b.MaxImpressionsPerUser)
+		if err != nil {
+			return result, false, err
+		}
+		if !counted {
+			return result, false, nil
+		}
+	}
+
+	if result.VariantId != 0 {
+		if err = s.repo.AssignVariant(result.VariantId, userId); err != nil {
+			logrus.Errorf("failed to record variant assignment: %s", err.Error())
+		}
+	}
+
+	s.recorder.Record(banner.Event{
+		BannerId:  b.Id,
+		VariantId: result.VariantId,
+		UserId:    userId,
+		Type:      banner.EventImpression,
+		CreatedAt: now,
+	})
+
+	return result, true, nil
+}
+
+func (s *BannerService) GetVariantReport(id int) ([]banner.VariantReport, error) {
+	return s.repo.GetVariantReport(id)
+}
+
+// GetAllBanners returns a page of the banners matching the filter. The next
+// page starts at the returned cursor, which is empty on the last page.
+func (s *BannerService) GetAllBanners(input banner.FilterInput) (banner.BannerPage, error) {
+	switch input.State {
+	case "", banner.StateScheduled, banner.StateLive, banner.StateExpired:
+	default:
+		return banner.BannerPage{}, banner.ErrInvalidState
+	}
+
+	if input.Status != "" && !isValidStatus(input.Status) {
+		return banner.BannerPage{}, banner.ErrInvalidStatus
+	}
+
+	if !isValidSort(input.Sort) {
+		return banner.BannerPage{}, banner.ErrInvalidSort
+	}
+
+	if input.Offset != nil {
+		return banner.BannerPage{}, banner.ErrInvalidOffset
+	}
+
+	if input.Limit == 0 {
+		input.Limit = defaultBannersLimit
+	}
+	if input.Limit < 1 || input.Limit > maxBannersLimit {
+		return banner.BannerPage{}, banner.ErrInvalidLimit
+	}
+
+	var after *banner.BannerCursor
+	if input.Cursor != "" {
+		var err error
+		if after, err = decodeCursor(input.Cursor, input.Sort); err != nil {
+			return banner.BannerPage{}, err
+		}
+	}
+
+	// One more banner than asked for tells whether there is a next page.
+	limit := input.Limit
+	input.Limit++
+	banners, total, err := s.repo.GetAllBanners(input, after)
+	if err != nil {
+		return banner.BannerPage{}, err
+	}
+
+	page := banner.BannerPage{Banners: banners, Total: total}
+	if len(banners) > limit {
+		page.Banners = banners[:limit]
+		page.NextCursor = encodeCursor(input.Sort, page.Banners[limit-1])
+	}
+
+	now := time.Now()
+	for i := range page.Banners {
+		page.Banners[i].State = page.Banners[i].ScheduleState(now)
+		page.Banners[i].MissingLocales = missingLocales(page.Banners[i], s.supported)
+	}
+
+	return page, nil
+}
+
+// checkBanner validates the banner before it is saved, normalizing its
+// translations and targeting. excludeId is the banner being updated, if any.
+func (s *BannerService) checkBanner(b *banner.Banner, excludeId int) error {
+	b.TagIds = dedupeIds(b.TagIds)
+
+	if err := checkSchedule(*b); err != nil {
+		return err
+	}
+
+	if err := checkFrequencyCap(*b); err != nil {
+		return err
+	}
+
+	if err := checkLocales(b); err != nil {
+		return err
+	}
+
+	if err := s.checkTargeting(b); err != nil {
+		return err
+	}
+
+	feature, err := s.checkReferences(*b)
+	if err != nil {
+		return err
+	}
+
+	if err = validateContent(feature.ContentSchema, *b); err != nil {
+		return err
+	}
+
+	if err = validateTemplates(*b); err != nil {
+		return err
+	}
+
+	return s.checkConflicts(b.TagIds, b.FeatureId, b.Priority, excludeId)
+}
+
+// checkReferences makes sure the banner's feature and tags exist and returns
+// the feature for further checks.
+func (s *BannerService) checkReferences(b banner.Banner) (banner.Feature, error) {
+	feature, err := s.features.GetFeatureById(b.FeatureId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return feature, &banner.UnknownIdsError{Entity: "feature", Ids: []int{b.FeatureId}}
+		}
+		return feature, err
+	}
+
+	missing, err := s.tags.GetMissingTagIds(b.TagIds)
+	if err != nil {
+		return feature, err
+	}
+
+	if len(missing) > 0 {
+		return feature, &banner.UnknownIdsError{Entity: "tag", Ids: missing}
+	}
+
+	return feature, nil
+}
+
+func (s *BannerService) checkConflicts(tagIds []int, featureId, priority, excludeId int) error {
+	bannerIds, err := s.repo.CheckBanner(tagIds, featureId, priority, excludeId)
+	if err != nil {
+		return err
+	}
+
+	if len(bannerIds) > 0 {
+		return &banner.ConflictError{BannerIds: bannerIds}
+	}
+
+	return nil
+}
+
+// conflictIds fills in the conflicting banners when the save lost a race to
+// another banner taking the same placement after checkConflicts passed.
+func (s *BannerService) conflictIds(err error, tagIds []int, featureId, priority, excludeId int) error {
+	var conflictErr *banner.ConflictError
+	if !errors.As(err, &conflictErr) || len(conflictErr.BannerIds) > 0 {
+		return err
+	}
+
+	bannerIds, checkErr := s.repo.CheckBanner(tagIds, featureId, priority, excludeId)
+	if checkErr != nil {
+		return err
+	}
+
+	return &banner.ConflictError{BannerIds: bannerIds}
 }
 
-func (s *BannerService) GetUserBanner(input banner.UserBannerInput, role string) (banner.Content, error) {
-	return s.repo.GetUserBanner(input, role)
+// dedupeIds drops repeated ids keeping the first occurrence order.
+func dedupeIds(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
 
-func (s *BannerService) GetAllBanners(input banner.FilterInput) ([]banner.Banner, error) {
-	return s.repo.GetAllBanners(input)
+func checkSchedule(b banner.Banner) error {
+	if b.StartsAt != nil && b.EndsAt != nil && !b.EndsAt.After(*b.StartsAt) {
+		return banner.ErrInvalidSchedule
+	}
+	return nil
 }
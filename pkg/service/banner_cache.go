@@ -0,0 +1,160 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	userBannerCachePrefix        = "user_banner:"
+	variantAssignmentCachePrefix = "variant_assignment:"
+)
+
+// CachedBannerRepository keeps user banner lookups in a Cache and drops them
+// whenever banners are changed through it.
+type CachedBannerRepository struct {
+	repository.Banner
+	cache Cache
+}
+
+func NewCachedBannerRepository(repo repository.Banner, cache Cache) *CachedBannerRepository {
+	return &CachedBannerRepository{
+		Banner: repo,
+		cache:  cache,
+	}
+}
+
+func (r *CachedBannerRepository) CreateBanner(banner banner.Banner, actor banner.Actor) (int, error) {
+	id, err := r.Banner.CreateBanner(banner, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return id, err
+}
+
+func (r *CachedBannerRepository) ImportBanners(banners []banner.Banner, actor banner.Actor) ([]int, error) {
+	ids, err := r.Banner.ImportBanners(banners, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return ids, err
+}
+
+func (r *CachedBannerRepository) UpdateBannerById(id int, banner banner.Banner, actor banner.Actor) error {
+	err := r.Banner.UpdateBannerById(id, banner, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return err
+}
+
+func (r *CachedBannerRepository) ActivateBannerRevision(id, version int, updatedAt string, actor banner.Actor) error {
+	err := r.Banner.ActivateBannerRevision(id, version, updatedAt, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return err
+}
+
+func (r *CachedBannerRepository) UpdateBannerStatus(id int, action, from, to, updatedAt string,
+	actor banner.Actor) error {
+	err := r.Banner.UpdateBannerStatus(id, action, from, to, updatedAt, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return err
+}
+
+func (r *CachedBannerRepository) DeleteBannerById(id, rowVersion int, actor banner.Actor) error {
+	err := r.Banner.DeleteBannerById(id, rowVersion, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return err
+}
+
+func (r *CachedBannerRepository) RestoreBannerById(id int, updatedAt string, actor banner.Actor) error {
+	err := r.Banner.RestoreBannerById(id, updatedAt, actor)
+	if err == nil {
+		r.invalidate()
+	}
+	return err
+}
+
+func (r *CachedBannerRepository) DeleteBannersBatch(input banner.BulkDeleteInput, limit int,
+	actor banner.Actor) ([]int, error) {
+	deleted, err := r.Banner.DeleteBannersBatch(input, limit, actor)
+	if err == nil && len(deleted) > 0 {
+		r.invalidate()
+	}
+	return deleted, err
+}
+
+// GetUserBanners serves banners from the cache unless the client asked for the
+// last revision, which always goes to the database and is never cached.
+// Cache failures are logged and fall back to the wrapped repository.
+func (r *CachedBannerRepository) GetUserBanners(input banner.UserBannerInput, role string) ([]banner.Banner, error) {
+	if input.UseLastRevision {
+		return r.Banner.GetUserBanners(input, role)
+	}
+
+	var banners []banner.Banner
+	key := fmt.Sprintf("%s%d:%d:%s", userBannerCachePrefix, input.TagId, input.FeatureId, role)
+
+	value, err := r.cache.Get(key)
+	if err == nil {
+		if err = json.Unmarshal(value, &banners); err == nil {
+			return banners, nil
+		}
+	}
+	if err != ErrCacheMiss {
+		logrus.Errorf("failed to read banner cache: %s", err.Error())
+	}
+
+	banners, err = r.Banner.GetUserBanners(input, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, err = json.Marshal(banners); err == nil {
+		err = r.cache.Set(key, value)
+	}
+	if err != nil {
+		logrus.Errorf("failed to write banner cache: %s", err.Error())
+	}
+
+	return banners, nil
+}
+
+// AssignVariant writes the assignment only when the cache does not remember
+// it yet. A variant's users never change, so the keys outlive invalidate.
+func (r *CachedBannerRepository) AssignVariant(variantId, userId int) error {
+	key := fmt.Sprintf("%s%d:%d", variantAssignmentCachePrefix, variantId, userId)
+
+	_, err := r.cache.Get(key)
+	if err == nil {
+		return nil
+	}
+	if err != ErrCacheMiss {
+		logrus.Errorf("failed to read banner cache: %s", err.Error())
+	}
+
+	if err = r.Banner.AssignVariant(variantId, userId); err != nil {
+		return err
+	}
+
+	if err = r.cache.Set(key, []byte{1}); err != nil {
+		logrus.Errorf("failed to write banner cache: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *CachedBannerRepository) invalidate() {
+	if err := r.cache.Clear(userBannerCachePrefix); err != nil {
+		logrus.Errorf("failed to invalidate banner cache: %s", err.Error())
+	}
+}
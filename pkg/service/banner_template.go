@@ -0,0 +1,170 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+	"database/sql"
+)
+
+type TemplateService struct {
+	repo    repository.BannerTemplate
+	banners *BannerService
+}
+
+func NewTemplateService(repo repository.BannerTemplate, banners *BannerService) *TemplateService {
+	return &TemplateService{repo: repo, banners: banners}
+}
+
+func (s *TemplateService) CreateBannerTemplate(template banner.BannerTemplate) (int, error) {
+	if err := s.checkTemplate(&template); err != nil {
+		return 0, err
+	}
+
+	return s.repo.CreateBannerTemplate(template)
+}
+
+func (s *TemplateService) GetAllBannerTemplates() ([]banner.BannerTemplate, error) {
+	return s.repo.GetAllBannerTemplates()
+}
+
+func (s *TemplateService) GetBannerTemplateById(id int) (banner.BannerTemplate, error) {
+	return s.repo.GetBannerTemplateById(id)
+}
+
+func (s *TemplateService) UpdateBannerTemplate(id int, input banner.UpdateBannerTemplateInput, updatedAt string) error {
+	template, err := s.repo.GetBannerTemplateById(id)
+	if err != nil {
+		return err
+	}
+
+	if input.Name != nil {
+		template.Name = *input.Name
+	}
+	if input.Description != nil {
+		template.Description = *input.Description
+	}
+	if input.FeatureId != nil {
+		template.FeatureId = *input.FeatureId
+	}
+	if input.Content != nil {
+		template.Content = input.Content
+	}
+	if input.LocalizedContent != nil {
+		template.LocalizedContent = input.LocalizedContent
+	}
+	if input.Targeting != nil {
+		template.Targeting = input.Targeting
+	}
+	if input.IsActive != nil {
+		template.IsActive = *input.IsActive
+	}
+	if input.MaxImpressionsPerUser != nil {
+		template.MaxImpressionsPerUser = input.MaxImpressionsPerUser
+	}
+	if input.ImpressionPeriod != nil {
+		template.ImpressionPeriod = input.ImpressionPeriod
+	}
+	if input.RolloutPercent != nil {
+		template.RolloutPercent = input.RolloutPercent
+	}
+	if input.Priority != nil {
+		template.Priority = *input.Priority
+	}
+	template.UpdatedAt = updatedAt
+
+	if err = s.checkTemplate(&template); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateBannerTemplate(id, template)
+}
+
+func (s *TemplateService) DeleteBannerTemplate(id int) error {
+	return s.repo.DeleteBannerTemplate(id)
+}
+
+// InstantiateBannerTemplate creates a banner with the template's content at
+// the given placement.
+func (s *TemplateService) InstantiateBannerTemplate(id int, input banner.InstantiateTemplateInput,
+	actor banner.Actor) (int, error) {
+	template, err := s.repo.GetBannerTemplateById(id)
+	if err != nil {
+		return 0, err
+	}
+
+	b := templateBanner(template)
+	b.TagIds = input.TagIds
+	if input.FeatureId != nil {
+		b.FeatureId = *input.FeatureId
+	}
+	if input.Priority != nil {
+		b.Priority = *input.Priority
+	}
+	if input.IsActive != nil {
+		b.IsActive = *input.IsActive
+	}
+	b.StartsAt = input.StartsAt
+	b.EndsAt = input.EndsAt
+	b.Status = input.Status
+	b.CreatedAt = input.CreatedAt
+	b.UpdatedAt = input.CreatedAt
+
+	return s.banners.CreateBanner(b, actor)
+}
+
+// checkTemplate runs the checks a banner created from the template would go
+// through, apart from those on its placement.
+func (s *TemplateService) checkTemplate(template *banner.BannerTemplate) error {
+	b := templateBanner(*template)
+
+	if err := checkFrequencyCap(b); err != nil {
+		return err
+	}
+
+	if err := checkLocales(&b); err != nil {
+		return err
+	}
+
+	if err := s.banners.checkTargeting(&b); err != nil {
+		return err
+	}
+
+	feature, err := s.banners.features.GetFeatureById(b.FeatureId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &banner.UnknownIdsError{Entity: "feature", Ids: []int{b.FeatureId}}
+		}
+		return err
+	}
+
+	if err = validateContent(feature.ContentSchema, b); err != nil {
+		return err
+	}
+
+	if err = validateTemplates(b); err != nil {
+		return err
+	}
+
+	template.LocalizedContent = b.LocalizedContent
+	template.Targeting = b.Targeting
+	return nil
+}
+
+func templateBanner(template banner.BannerTemplate) banner.Banner {
+	rolloutPercent := 100
+	if template.RolloutPercent != nil {
+		rolloutPercent = *template.RolloutPercent
+	}
+
+	return banner.Banner{
+		FeatureId:             template.FeatureId,
+		Content:               template.Content,
+		LocalizedContent:      template.LocalizedContent,
+		Targeting:             template.Targeting,
+		IsActive:              template.IsActive,
+		MaxImpressionsPerUser: template.MaxImpressionsPerUser,
+		ImpressionPeriod:      template.ImpressionPeriod,
+		RolloutPercent:        rolloutPercent,
+		Priority:              template.Priority,
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrCacheMiss = errors.New("cache miss")
+
+type Cache interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte) error
+	Clear(prefix string) error
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache drops expired entries once per ttl on writes, so keys that are
+// never read again don't pile up.
+type MemoryCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+	sweptAt time.Time
+}
+
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		ttl:     ttl,
+		entries: make(map[string]memoryEntry),
+		sweptAt: time.Now(),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, ErrCacheMiss
+	}
+	return entry.value, nil
+}
+
+func (c *MemoryCache) Set(key string, value []byte) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	if now.Sub(c.sweptAt) >= c.ttl {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.sweptAt = now
+	}
+	c.entries[key] = memoryEntry{value: value, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *MemoryCache) Clear(prefix string) error {
+	c.mu.Lock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+	return nil
+}
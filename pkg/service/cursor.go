@@ -0,0 +1,56 @@
+package service
+
+import (
+	"banner"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBannersLimit = 100
+	maxBannersLimit     = 1000
+)
+
+func isValidSort(sort string) bool {
+	switch strings.TrimPrefix(sort, "-") {
+	case "", banner.SortById, banner.SortByCreatedAt, banner.SortByUpdatedAt, banner.SortByPriority:
+		return sort != "-"
+	default:
+		return false
+	}
+}
+
+// encodeCursor points after b in banners sorted by sort. Cursors are opaque
+// to clients.
+func encodeCursor(sort string, b banner.Banner) string {
+	cursor := banner.BannerCursor{Sort: sort, Id: b.Id}
+
+	switch strings.TrimPrefix(sort, "-") {
+	case banner.SortByCreatedAt:
+		cursor.Value = b.CreatedAt
+	case banner.SortByUpdatedAt:
+		cursor.Value = b.UpdatedAt
+	case banner.SortByPriority:
+		cursor.Value = strconv.Itoa(b.Priority)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor made by encodeCursor for the same sort.
+func decodeCursor(value, sort string) (*banner.BannerCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, banner.ErrInvalidCursor
+	}
+
+	var cursor banner.BannerCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return nil, banner.ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	eventBufferSize    = 10000
+	eventBatchSize     = 500
+	eventFlushInterval = time.Second
+)
+
+// EventRecorder buffers banner events in memory and writes them to the
+// repository in batches from a background goroutine.
+type EventRecorder struct {
+	repo   repository.Event
+	events chan banner.Event
+	done   chan struct{}
+}
+
+func NewEventRecorder(repo repository.Event) *EventRecorder {
+	r := &EventRecorder{
+		repo:   repo,
+		events: make(chan banner.Event, eventBufferSize),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record never blocks the caller: when the buffer is full the event is dropped.
+func (r *EventRecorder) Record(event banner.Event) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		logrus.Errorf("event buffer is full, dropping %s event for banner %d", event.Type, event.BannerId)
+	}
+}
+
+// Close flushes buffered events and stops the background writer.
+func (r *EventRecorder) Close() {
+	close(r.events)
+	<-r.done
+}
+
+func (r *EventRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(eventFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]banner.Event, 0, eventBatchSize)
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= eventBatchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		}
+	}
+}
+
+func (r *EventRecorder) flush(batch []banner.Event) []banner.Event {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := r.repo.InsertEvents(batch); err != nil {
+		logrus.Errorf("failed to write %d events: %s", len(batch), err.Error())
+	}
+
+	return batch[:0]
+}
+
+type EventService struct {
+	repo     repository.Event
+	banners  repository.Banner
+	recorder *EventRecorder
+}
+
+func NewEventService(repo repository.Event, banners repository.Banner, recorder *EventRecorder) *EventService {
+	return &EventService{repo: repo, banners: banners, recorder: recorder}
+}
+
+func (s *EventService) RecordClick(bannerId int, input banner.ClickInput, userId int) error {
+	if _, err := s.banners.GetBannerById(bannerId); err != nil {
+		return err
+	}
+
+	s.recorder.Record(banner.Event{
+		BannerId:  bannerId,
+		VariantId: input.VariantId,
+		UserId:    userId,
+		Type:      banner.EventClick,
+	})
+	return nil
+}
+
+func (s *EventService) GetBannerStats(bannerId int) ([]banner.DailyStats, error) {
+	if _, err := s.banners.GetBannerById(bannerId); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.GetDailyStats(bannerId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		if stats[i].Impressions > 0 {
+			stats[i].CTR = float64(stats[i].Clicks) / float64(stats[i].Impressions)
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+)
+
+type FeatureService struct {
+	repo repository.Feature
+}
+
+func NewFeatureService(repo repository.Feature) *FeatureService {
+	return &FeatureService{repo: repo}
+}
+
+func (s *FeatureService) CreateFeature(feature banner.Feature) (int, error) {
+	if feature.ContentSchema != nil {
+		if _, err := compileSchema(feature.ContentSchema); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.repo.CreateFeature(feature)
+}
+
+func (s *FeatureService) GetAllFeatures() ([]banner.Feature, error) {
+	return s.repo.GetAllFeatures()
+}
+
+func (s *FeatureService) GetFeatureById(id int) (banner.Feature, error) {
+	return s.repo.GetFeatureById(id)
+}
+
+func (s *FeatureService) UpdateFeature(id int, input banner.UpdateFeatureInput) error {
+	if input.ContentSchema != nil {
+		if _, err := compileSchema(input.ContentSchema); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.UpdateFeature(id, input)
+}
+
+func (s *FeatureService) DeleteFeature(id int) error {
+	return s.repo.DeleteFeature(id)
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"banner"
+	"time"
+)
+
+// capWindowStart returns the start of the frequency cap window that now falls
+// into. Lifetime caps share a single window starting at the Unix epoch.
+func capWindowStart(b banner.Banner, now time.Time) time.Time {
+	if b.ImpressionPeriod == nil {
+		return time.Unix(0, 0).UTC()
+	}
+	return now.Truncate(time.Duration(*b.ImpressionPeriod) * time.Second).UTC()
+}
+
+func checkFrequencyCap(b banner.Banner) error {
+	if b.ImpressionPeriod != nil && b.MaxImpressionsPerUser == nil {
+		return banner.ErrInvalidCap
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type IdempotencyService struct {
+	repo repository.Idempotency
+	ttl  time.Duration
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewIdempotencyService drops expired keys in the background.
+func NewIdempotencyService(repo repository.Idempotency, ttl time.Duration) *IdempotencyService {
+	s := &IdempotencyService{
+		repo: repo,
+		ttl:  ttl,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		runEvery(purgeInterval, s.stop, s.purge)
+	}()
+
+	return s
+}
+
+func (s *IdempotencyService) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *IdempotencyService) purge() {
+	deleted, err := s.repo.DeleteExpiredIdempotencyKeys(time.Now().Add(-s.ttl))
+	if err != nil {
+		logrus.Errorf("failed to purge expired idempotency keys: %s", err.Error())
+		return
+	}
+
+	if deleted > 0 {
+		logrus.Printf("purged %d expired idempotency keys", deleted)
+	}
+}
+
+// BeginRequest claims the key for the request with the given fingerprint. It
+// returns the stored record when the request already completed, and nil when
+// the caller holds the key and has to run the request.
+func (s *IdempotencyService) BeginRequest(userId int, key, fingerprint string) (*banner.IdempotencyRecord, error) {
+	record := banner.IdempotencyRecord{UserId: userId, Key: key, Fingerprint: fingerprint}
+	existing, claimed, err := s.repo.ReserveIdempotencyKey(record, time.Now().Add(-s.ttl))
+	if err != nil {
+		return nil, err
+	}
+
+	if claimed {
+		return nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, banner.ErrKeyReused
+	}
+
+	if existing.StatusCode == 0 {
+		return nil, banner.ErrKeyInProgress
+	}
+
+	return &existing, nil
+}
+
+func (s *IdempotencyService) CompleteRequest(record banner.IdempotencyRecord) error {
+	return s.repo.CompleteIdempotencyKey(record)
+}
+
+func (s *IdempotencyService) ReleaseRequest(userId int, key string) error {
+	return s.repo.ReleaseIdempotencyKey(userId, key)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"banner"
+	"errors"
+	"fmt"
+)
+
+const exportBatchSize = 100
+
+// ExportBanners passes the banners matching the filter, with their variants,
+// to write in batches, so that they don't have to be held in memory at once.
+// The input's cursor and limit are ignored.
+func (s *BannerService) ExportBanners(input banner.FilterInput, write func([]banner.Banner) error) error {
+	input.Cursor = ""
+	input.Limit = exportBatchSize
+	for {
+		page, err := s.GetAllBanners(input)
+		if err != nil {
+			return err
+		}
+
+		for i := range page.Banners {
+			if page.Banners[i].Variants, err = s.repo.GetBannerVariants(page.Banners[i].Id); err != nil {
+				return err
+			}
+		}
+
+		if len(page.Banners) > 0 {
+			if err = write(page.Banners); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		input.Cursor = page.NextCursor
+	}
+}
+
+// ImportBanners validates every row and creates the banners in one
+// transaction if all of them are valid. Rows must not conflict with existing
+// banners nor with each other.
+func (s *BannerService) ImportBanners(rows []banner.ImportRow, dryRun bool,
+	actor banner.Actor) (banner.ImportResult, error) {
+	result := banner.ImportResult{Total: len(rows), DryRun: dryRun}
+
+	type place struct{ tagId, featureId, priority int }
+	places := make(map[place]int)
+	banners := make([]banner.Banner, 0, len(rows))
+
+	for _, row := range rows {
+		if row.Error != "" {
+			result.Errors = append(result.Errors, banner.ImportRowError{Line: row.Line, Error: row.Error})
+			continue
+		}
+
+		b := row.Banner
+		b.Status = banner.StatusDraft
+
+		if err := s.checkBanner(&b, 0); err != nil {
+			if !isInvalidBanner(err) {
+				return result, err
+			}
+
+			rowErr := banner.ImportRowError{Line: row.Line, Error: err.Error()}
+			var conflictErr *banner.ConflictError
+			if errors.As(err, &conflictErr) {
+				rowErr.BannerIds = conflictErr.BannerIds
+			}
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+
+		for _, tagId := range b.TagIds {
+			p := place{tagId: tagId, featureId: b.FeatureId, priority: b.Priority}
+			if line, ok := places[p]; ok {
+				result.Errors = append(result.Errors, banner.ImportRowError{
+					Line:  row.Line,
+					Error: fmt.Sprintf("banner conflicts with the one on line %d", line),
+				})
+				break
+			}
+			places[p] = row.Line
+		}
+
+		banners = append(banners, b)
+	}
+
+	if len(result.Errors) > 0 || dryRun {
+		return result, nil
+	}
+
+	ids, err := s.repo.ImportBanners(banners, actor)
+	if err != nil {
+		return result, err
+	}
+
+	result.BannerIds = ids
+	return result, nil
+}
+
+// isInvalidBanner tells errors about the banner's data from failures to
+// check it.
+func isInvalidBanner(err error) bool {
+	var unknownIdsErr *banner.UnknownIdsError
+	var conflictErr *banner.ConflictError
+
+	return errors.As(err, &unknownIdsErr) ||
+		errors.As(err, &conflictErr) ||
+		errors.Is(err, banner.ErrInvalidSchedule) ||
+		errors.Is(err, banner.ErrInvalidCap) ||
+		errors.Is(err, banner.ErrInvalidSchema) ||
+		errors.Is(err, banner.ErrInvalidContent) ||
+		errors.Is(err, banner.ErrInvalidLocale) ||
+		errors.Is(err, banner.ErrInvalidTemplate) ||
+		errors.Is(err, banner.ErrInvalidRule)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+	"database/sql"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	bulkDeleteJobType   = "bulk_delete"
+	bulkDeleteBatchSize = 100
+	staleJobsInterval   = time.Minute
+	// staleJobAfter is how long a job may go without progress before it is
+	// considered abandoned by the instance running it.
+	staleJobAfter = 10 * time.Minute
+)
+
+var (
+	errJobInterrupted = errors.New("job was interrupted by a shutdown")
+	errJobStale       = errors.New("job made no progress, the instance running it is gone")
+)
+
+type JobService struct {
+	repo    repository.Job
+	banners Banner
+	stop    chan struct{}
+	running sync.WaitGroup
+}
+
+// NewJobService fails jobs that stopped making progress in the background.
+// Every instance does so, which also covers jobs of instances that crashed.
+func NewJobService(repo repository.Job, banners Banner) *JobService {
+	s := &JobService{repo: repo, banners: banners, stop: make(chan struct{})}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		runEvery(staleJobsInterval, s.stop, s.failStaleJobs)
+	}()
+
+	return s
+}
+
+// Close stops running jobs after their current batch and waits for them.
+func (s *JobService) Close() {
+	close(s.stop)
+	s.running.Wait()
+}
+
+func (s *JobService) failStaleJobs() {
+	failed, err := s.repo.FailStaleJobs(time.Now().Add(-staleJobAfter), errJobStale.Error())
+	if err != nil {
+		logrus.Errorf("failed to fail stale jobs: %s", err.Error())
+		return
+	}
+
+	if failed > 0 {
+		logrus.Warnf("marked %d stale jobs failed", failed)
+	}
+}
+
+func (s *JobService) GetJobById(id int) (banner.Job, error) {
+	return s.repo.GetJobById(id)
+}
+
+// CreateBulkDeleteJob records a pending job and deletes the matching banners
+// in the background, so the caller only has to poll the job for progress.
+func (s *JobService) CreateBulkDeleteJob(input banner.BulkDeleteInput, actor banner.Actor) (int, error) {
+	job := banner.Job{
+		Type:      bulkDeleteJobType,
+		Status:    banner.JobPending,
+		TagId:     input.TagId,
+		FeatureId: input.FeatureId,
+	}
+
+	id, err := s.repo.CreateJob(job)
+	if err != nil {
+		return 0, err
+	}
+	job.Id = id
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.runBulkDelete(job, input, actor)
+	}()
+
+	return id, nil
+}
+
+func (s *JobService) runBulkDelete(job banner.Job, input banner.BulkDeleteInput, actor banner.Actor) {
+	total, err := s.banners.CountBanners(input)
+	if err != nil {
+		s.failJob(job, err)
+		return
+	}
+
+	job.Status = banner.JobRunning
+	job.Total = total
+	if err = s.repo.UpdateJob(job); err != nil {
+		s.failJob(job, err)
+		return
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			s.failJob(job, errJobInterrupted)
+			return
+		default:
+		}
+
+		deleted, err := s.banners.DeleteBannersBatch(input, bulkDeleteBatchSize, actor)
+		if err != nil {
+			s.failJob(job, err)
+			return
+		}
+
+		if len(deleted) == 0 {
+			break
+		}
+
+		job.Processed += len(deleted)
+		if err = s.repo.UpdateJob(job); err == sql.ErrNoRows {
+			logrus.Warnf("job %d was failed as stale, stopping it", job.Id)
+			return
+		} else if err != nil {
+			s.failJob(job, err)
+			return
+		}
+	}
+
+	job.Status = banner.JobDone
+	if err = s.repo.UpdateJob(job); err != nil {
+		logrus.Errorf("failed to finish job %d: %s", job.Id, err.Error())
+	}
+}
+
+func (s *JobService) failJob(job banner.Job, err error) {
+	logrus.Errorf("job %d failed: %s", job.Id, err.Error())
+
+	job.Status = banner.JobFailed
+	job.Error = err.Error()
+	if err = s.repo.UpdateJob(job); err != nil {
+		logrus.Errorf("failed to update job %d: %s", job.Id, err.Error())
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"banner"
+	"regexp"
+	"strings"
+)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// NormalizeLocale lowercases a locale tag and accepts both "pt_BR" and
+// "pt-BR" spellings.
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ParseLocales parses a comma separated list of locales such as the
+// LOCALE_FALLBACK and SUPPORTED_LOCALES settings.
+func ParseLocales(value string) []string {
+	var locales []string
+	for _, locale := range strings.Split(value, ",") {
+		if locale = NormalizeLocale(locale); locale != "" {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
+
+// checkLocales normalizes the locale keys of the banner's localized content
+// and rejects malformed ones.
+func checkLocales(b *banner.Banner) error {
+	if len(b.LocalizedContent) == 0 {
+		return nil
+	}
+
+	localized := make(banner.LocalizedContent, len(b.LocalizedContent))
+	for locale, content := range b.LocalizedContent {
+		normalized := NormalizeLocale(locale)
+		if !localePattern.MatchString(normalized) || content == nil {
+			return banner.ErrInvalidLocale
+		}
+		if _, ok := localized[normalized]; ok {
+			return banner.ErrInvalidLocale
+		}
+		localized[normalized] = content
+	}
+	b.LocalizedContent = localized
+
+	return nil
+}
+
+// localize picks the content for the first locale the banner is translated
+// to. Every requested locale is tried before its base language, then the
+// fallback chain; the default content is used when none of them match.
+func localize(b banner.Banner, locales, fallback []string) (banner.Content, string) {
+	if len(b.LocalizedContent) == 0 {
+		return b.Content, ""
+	}
+
+	var candidates []string
+	for _, locale := range locales {
+		locale = NormalizeLocale(locale)
+		candidates = append(candidates, locale)
+		if i := strings.Index(locale, "-"); i > 0 {
+			candidates = append(candidates, locale[:i])
+		}
+	}
+	candidates = append(candidates, fallback...)
+
+	for _, locale := range candidates {
+		if content, ok := b.LocalizedContent[locale]; ok {
+			return content, locale
+		}
+	}
+
+	return b.Content, ""
+}
+
+// missingLocales lists the supported locales the banner has no translation
+// for.
+func missingLocales(b banner.Banner, supported []string) []string {
+	var missing []string
+	for _, locale := range supported {
+		if _, ok := b.LocalizedContent[locale]; !ok {
+			missing = append(missing, locale)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"banner/pkg/repository"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const purgeInterval = time.Hour
+
+// BannerPurger hard deletes banners once they have been soft deleted for
+// longer than the retention period.
+type BannerPurger struct {
+	repo      repository.Banner
+	retention time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewBannerPurger starts purging in the background unless retention is zero.
+func NewBannerPurger(repo repository.Banner, retention time.Duration) *BannerPurger {
+	p := &BannerPurger{
+		repo:      repo,
+		retention: retention,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if retention > 0 {
+		go func() {
+			defer close(p.done)
+			runEvery(purgeInterval, p.stop, p.purge)
+		}()
+	} else {
+		close(p.done)
+	}
+
+	return p
+}
+
+func (p *BannerPurger) Close() {
+	close(p.stop)
+	<-p.done
+}
+
+// runEvery calls fn right away and then every interval until stop is closed.
+func runEvery(interval time.Duration, stop <-chan struct{}, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (p *BannerPurger) purge() {
+	ids, err := p.repo.PurgeDeletedBanners(time.Now().Add(-p.retention))
+	if err != nil {
+		logrus.Errorf("failed to purge deleted banners: %s", err.Error())
+		return
+	}
+
+	if len(ids) > 0 {
+		logrus.Printf("purged %d deleted banners", len(ids))
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const redisScanCount = 100
+
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+func (c *RedisCache) Get(key string) ([]byte, error) {
+	value, err := c.client.Get(context.Background(), key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
+	return value, err
+}
+
+func (c *RedisCache) Set(key string, value []byte) error {
+	return c.client.Set(context.Background(), key, value, c.ttl).Err()
+}
+
+func (c *RedisCache) Clear(prefix string) error {
+	ctx := context.Background()
+	iter := c.client.Scan(ctx, 0, prefix+"*", redisScanCount).Iterator()
+
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
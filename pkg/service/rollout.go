@@ -0,0 +1,22 @@
+package service
+
+import (
+	"banner"
+	"hash/fnv"
+	"strconv"
+)
+
+const maxRolloutPercent = 100
+
+// inRollout decides whether the user falls into the banner's rollout cohort.
+// The hash is salted differently from variant assignment, so cohorts and
+// variants stay independent, and raising the percentage only adds users.
+func inRollout(b banner.Banner, userId int) bool {
+	if b.RolloutPercent >= maxRolloutPercent {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte("rollout:" + strconv.Itoa(b.Id) + ":" + strconv.Itoa(userId)))
+	return int(h.Sum32()%maxRolloutPercent) < b.RolloutPercent
+}
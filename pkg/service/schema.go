@@ -0,0 +1,67 @@
+package service
+
+import (
+	"banner"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"io"
+)
+
+const schemaURL = "mem://schemas/content.json"
+
+// compileSchema compiles a feature's content schema. References to external
+// documents are refused so a schema can't make the service read files or
+// fetch URLs.
+func compileSchema(schema banner.Schema) (*jsonschema.Schema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("external reference %q is not allowed", url)
+	}
+
+	if err = compiler.AddResource(schemaURL, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%w: %s", banner.ErrInvalidSchema, err.Error())
+	}
+
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", banner.ErrInvalidSchema, err.Error())
+	}
+
+	return compiled, nil
+}
+
+// validateContent checks the banner content, its translations and the content
+// of every variant against the feature schema. Features without a schema accept any object.
+func validateContent(schema banner.Schema, b banner.Banner) error {
+	if schema == nil {
+		return nil
+	}
+
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	contents := []banner.Content{b.Content}
+	for _, content := range b.LocalizedContent {
+		contents = append(contents, content)
+	}
+	for _, v := range b.Variants {
+		contents = append(contents, v.Content)
+	}
+
+	for _, content := range contents {
+		if err = compiled.Validate(map[string]interface{}(content)); err != nil {
+			return fmt.Errorf("%w: %s", banner.ErrInvalidContent, err.Error())
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"banner"
 	"banner/pkg/repository"
+	"time"
 )
 
 type Authorization interface {
@@ -10,27 +11,143 @@ type Authorization interface {
 	CheckNickNameAndEmail(nickname, email string) (int, error)
 	GetPasswordHash(nickname string) (string, error)
 	GenerateToken(nickname, passwordHash string) (string, error)
-	ParseToken(accessToken string) (int, string, error)
+	ParseToken(accessToken string) (banner.Claims, error)
 }
 
 type Banner interface {
-	CheckBanner(tagIds []int, featureId int) (bool, error)
-	CreateBanner(banner banner.Banner) (int, error)
+	CheckBanner(tagIds []int, featureId, priority, excludeId int) ([]int, error)
+	CreateBanner(banner banner.Banner, actor banner.Actor) (int, error)
+	CloneBanner(id int, input banner.CloneBannerInput, actor banner.Actor) (int, error)
+	ImportBanners(rows []banner.ImportRow, dryRun bool, actor banner.Actor) (banner.ImportResult, error)
 	GetBannerById(id int) (banner.Banner, error)
-	UpdateBannerById(id int, banner banner.Banner) error
-	DeleteBannerById(id int) error
-	GetUserBanner(input banner.UserBannerInput, role string) (banner.Content, error)
-	GetAllBanners(input banner.FilterInput) ([]banner.Banner, error)
+	UpdateBannerById(id int, banner banner.Banner, actor banner.Actor) error
+	GetBannerRevisions(id int) ([]banner.Revision, error)
+	ActivateBannerRevision(id, version int, updatedAt string, actor banner.Actor) error
+	ChangeBannerStatus(id int, action, updatedAt string, actor banner.Actor) error
+	DeleteBannerById(id, rowVersion int, actor banner.Actor) error
+	RestoreBannerById(id int, updatedAt string, actor banner.Actor) error
+	CountBanners(input banner.BulkDeleteInput) (int, error)
+	DeleteBannersBatch(input banner.BulkDeleteInput, limit int, actor banner.Actor) ([]int, error)
+	GetUserBanner(input banner.UserBannerInput, userId int, role string) (banner.UserBanner, error)
+	GetUserBanners(input banner.UserBannerInput, limit, userId int, role string) ([]banner.UserBanner, error)
+	GetVariantReport(id int) ([]banner.VariantReport, error)
+	GetAllBanners(input banner.FilterInput) (banner.BannerPage, error)
+	ExportBanners(input banner.FilterInput, write func([]banner.Banner) error) error
+	ValidateTargeting(input banner.ValidateRuleInput) (banner.RuleCheck, error)
+	Simulate(input banner.SimulateInput) (banner.Simulation, error)
+}
+
+type BannerTemplate interface {
+	CreateBannerTemplate(template banner.BannerTemplate) (int, error)
+	GetAllBannerTemplates() ([]banner.BannerTemplate, error)
+	GetBannerTemplateById(id int) (banner.BannerTemplate, error)
+	UpdateBannerTemplate(id int, input banner.UpdateBannerTemplateInput, updatedAt string) error
+	DeleteBannerTemplate(id int) error
+	InstantiateBannerTemplate(id int, input banner.InstantiateTemplateInput, actor banner.Actor) (int, error)
+}
+
+type Job interface {
+	CreateBulkDeleteJob(input banner.BulkDeleteInput, actor banner.Actor) (int, error)
+	GetJobById(id int) (banner.Job, error)
+}
+
+type Feature interface {
+	CreateFeature(feature banner.Feature) (int, error)
+	GetAllFeatures() ([]banner.Feature, error)
+	GetFeatureById(id int) (banner.Feature, error)
+	UpdateFeature(id int, input banner.UpdateFeatureInput) error
+	DeleteFeature(id int) error
+}
+
+type Tag interface {
+	CreateTag(tag banner.Tag) (int, error)
+	GetAllTags() ([]banner.Tag, error)
+	GetTagById(id int) (banner.Tag, error)
+	UpdateTag(id int, input banner.UpdateTagInput) error
+	DeleteTag(id int) error
+}
+
+type Event interface {
+	RecordClick(bannerId int, input banner.ClickInput, userId int) error
+	GetBannerStats(bannerId int) ([]banner.DailyStats, error)
+}
+
+type Audit interface {
+	GetAuditEntries(filter banner.AuditFilter) ([]banner.AuditEntry, error)
+}
+
+type Idempotency interface {
+	BeginRequest(userId int, key, fingerprint string) (*banner.IdempotencyRecord, error)
+	CompleteRequest(record banner.IdempotencyRecord) error
+	ReleaseRequest(userId int, key string) error
 }
 
 type Service struct {
 	Authorization
 	Banner
+	BannerTemplate
+	Job
+	Feature
+	Tag
+	Event
+	Audit
+	Idempotency
+
+	jobs     *JobService
+	keys     *IdempotencyService
+	recorder *EventRecorder
+	purger   *BannerPurger
+}
+
+type Config struct {
+	// FallbackLocales is the chain of locales tried when none of the user's
+	// locales has a translation.
+	FallbackLocales []string
+	// SupportedLocales are the locales every banner is expected to be
+	// translated to; the banner list reports the missing ones.
+	SupportedLocales []string
+	// DeletedRetention is how long soft deleted banners can be restored
+	// before they are purged. Zero disables purging.
+	DeletedRetention time.Duration
+	// IdempotencyTTL is how long responses are kept for replaying requests
+	// retried with the same Idempotency-Key.
+	IdempotencyTTL time.Duration
 }
 
-func NewService(repos *repository.Repository) *Service {
+func NewService(repos *repository.Repository, cache Cache, config Config) *Service {
+	var bannerRepo repository.Banner = repos.Banner
+	if cache != nil {
+		bannerRepo = NewCachedBannerRepository(repos.Banner, cache)
+	}
+	recorder := NewEventRecorder(repos.Event)
+	auditService := NewAuditService(repos.Audit)
+	bannerService := NewBannerService(bannerRepo, repos.Feature, repos.Tag, repos.Impression, recorder,
+		config.FallbackLocales, config.SupportedLocales)
+
+	jobService := NewJobService(repos.Job, bannerService)
+	idempotencyService := NewIdempotencyService(repos.Idempotency, config.IdempotencyTTL)
+
 	return &Service{
-		Authorization: NewAuthService(repos.Authorization),
-		Banner:        NewBannerService(repos.Banner),
+		Authorization:  NewAuthService(repos.Authorization),
+		Banner:         bannerService,
+		BannerTemplate: NewTemplateService(repos.BannerTemplate, bannerService),
+		Job:            jobService,
+		Feature:        NewFeatureService(repos.Feature),
+		Tag:            NewTagService(repos.Tag),
+		Event:          NewEventService(repos.Event, bannerRepo, recorder),
+		Audit:          auditService,
+		Idempotency:    idempotencyService,
+		jobs:           jobService,
+		keys:           idempotencyService,
+		recorder:       recorder,
+		purger:         NewBannerPurger(repos.Banner, config.DeletedRetention),
 	}
 }
+
+// Close stops background work, flushing what must not be lost on shutdown.
+func (s *Service) Close() {
+	s.jobs.Close()
+	s.keys.Close()
+	s.purger.Close()
+	s.recorder.Close()
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"banner"
+	"banner/pkg/repository"
+)
+
+type TagService struct {
+	repo repository.Tag
+}
+
+func NewTagService(repo repository.Tag) *TagService {
+	return &TagService{repo: repo}
+}
+
+func (s *TagService) CreateTag(tag banner.Tag) (int, error) {
+	return s.repo.CreateTag(tag)
+}
+
+func (s *TagService) GetAllTags() ([]banner.Tag, error) {
+	return s.repo.GetAllTags()
+}
+
+func (s *TagService) GetTagById(id int) (banner.Tag, error) {
+	return s.repo.GetTagById(id)
+}
+
+func (s *TagService) UpdateTag(id int, input banner.UpdateTagInput) error {
+	return s.repo.UpdateTag(id, input)
+}
+
+func (s *TagService) DeleteTag(id int) error {
+	return s.repo.DeleteTag(id)
+}
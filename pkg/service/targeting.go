@@ -0,0 +1,296 @@
+package service
+
+import (
+	"banner"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxRuleDepth = 8
+	maxRuleNodes = 100
+)
+
+// conditionCount reports how many conditions the rule node sets. A valid node
+// sets exactly one, an empty top-level rule means "no targeting".
+func conditionCount(r banner.Rule) int {
+	count := 0
+	if r.All != nil {
+		count++
+	}
+	if r.Any != nil {
+		count++
+	}
+	if r.Not != nil {
+		count++
+	}
+	if r.Tag != nil {
+		count++
+	}
+	if r.AppVersion != nil {
+		count++
+	}
+	if r.Platform != nil {
+		count++
+	}
+	if r.Country != nil {
+		count++
+	}
+	return count
+}
+
+// validateRule checks the rule structure and returns the tag ids it refers
+// to so their existence can be checked by the caller.
+func validateRule(r banner.Rule) ([]int, error) {
+	var tagIds []int
+	nodes := 0
+
+	var walk func(r banner.Rule, depth int) error
+	walk = func(r banner.Rule, depth int) error {
+		nodes++
+		if depth > maxRuleDepth || nodes > maxRuleNodes {
+			return fmt.Errorf("%w: rule is nested deeper than %d or has more than %d nodes",
+				banner.ErrInvalidRule, maxRuleDepth, maxRuleNodes)
+		}
+
+		if conditionCount(r) != 1 {
+			return fmt.Errorf("%w: every rule must set exactly one of all, any, not, tag, app_version, "+
+				"platform, country", banner.ErrInvalidRule)
+		}
+
+		switch {
+		case r.All != nil || r.Any != nil:
+			children := r.All
+			if r.Any != nil {
+				children = r.Any
+			}
+			if len(children) == 0 {
+				return fmt.Errorf("%w: all and any need at least one rule", banner.ErrInvalidRule)
+			}
+			for _, child := range children {
+				if err := walk(child, depth+1); err != nil {
+					return err
+				}
+			}
+		case r.Not != nil:
+			return walk(*r.Not, depth+1)
+		case r.Tag != nil:
+			tagIds = append(tagIds, *r.Tag)
+		case r.AppVersion != nil:
+			return validateVersionRange(*r.AppVersion)
+		case r.Platform != nil:
+			return validateValues("platform", r.Platform)
+		case r.Country != nil:
+			return validateValues("country", r.Country)
+		}
+
+		return nil
+	}
+
+	if err := walk(r, 1); err != nil {
+		return nil, err
+	}
+	return tagIds, nil
+}
+
+func validateVersionRange(v banner.VersionRange) error {
+	if v.Min == "" && v.Max == "" {
+		return fmt.Errorf("%w: app_version needs min or max", banner.ErrInvalidRule)
+	}
+
+	var bounds [][]int
+	for _, s := range []string{v.Min, v.Max} {
+		if s == "" {
+			continue
+		}
+		version, err := parseVersion(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s", banner.ErrInvalidRule, err.Error())
+		}
+		bounds = append(bounds, version)
+	}
+
+	if len(bounds) == 2 && compareVersions(bounds[0], bounds[1]) >= 0 {
+		return fmt.Errorf("%w: app_version min must be below max", banner.ErrInvalidRule)
+	}
+
+	return nil
+}
+
+func validateValues(name string, values []string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%w: %s needs at least one value", banner.ErrInvalidRule, name)
+	}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%w: %s values must not be empty", banner.ErrInvalidRule, name)
+		}
+	}
+	return nil
+}
+
+// parseVersion parses dotted numeric versions such as 2.10.1.
+func parseVersion(s string) ([]int, error) {
+	parts := strings.Split(s, ".")
+	version := make([]int, len(parts))
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		version[i] = number
+	}
+	return version, nil
+}
+
+// compareVersions compares versions part by part, missing parts count as 0.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// matchRule evaluates the rule against the user. The placement tag of the
+// request counts as one of the user's tags. Conditions on attributes the
+// user didn't send never match.
+func matchRule(r banner.Rule, attributes banner.UserAttributes, placementTag int) bool {
+	switch {
+	case r.All != nil:
+		for _, child := range r.All {
+			if !matchRule(child, attributes, placementTag) {
+				return false
+			}
+		}
+		return true
+	case r.Any != nil:
+		for _, child := range r.Any {
+			if matchRule(child, attributes, placementTag) {
+				return true
+			}
+		}
+		return false
+	case r.Not != nil:
+		return !matchRule(*r.Not, attributes, placementTag)
+	case r.Tag != nil:
+		if *r.Tag == placementTag {
+			return true
+		}
+		for _, tagId := range attributes.TagIds {
+			if tagId == *r.Tag {
+				return true
+			}
+		}
+		return false
+	case r.AppVersion != nil:
+		return matchVersion(*r.AppVersion, attributes.AppVersion)
+	case r.Platform != nil:
+		return matchValue(r.Platform, attributes.Platform)
+	case r.Country != nil:
+		return matchValue(r.Country, attributes.Country)
+	default:
+		return true
+	}
+}
+
+func matchVersion(v banner.VersionRange, appVersion string) bool {
+	version, err := parseVersion(appVersion)
+	if err != nil {
+		return false
+	}
+
+	if v.Min != "" {
+		if lower, err := parseVersion(v.Min); err != nil || compareVersions(version, lower) < 0 {
+			return false
+		}
+	}
+	if v.Max != "" {
+		if upper, err := parseVersion(v.Max); err != nil || compareVersions(version, upper) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchValue(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTargeting clears empty rules and validates the rest, including that
+// the tags they refer to exist.
+func (s *BannerService) checkTargeting(b *banner.Banner) error {
+	if b.Targeting == nil {
+		return nil
+	}
+
+	if conditionCount(*b.Targeting) == 0 {
+		b.Targeting = nil
+		return nil
+	}
+
+	return s.checkRule(*b.Targeting)
+}
+
+func (s *BannerService) checkRule(r banner.Rule) error {
+	tagIds, err := validateRule(r)
+	if err != nil {
+		return err
+	}
+
+	if len(tagIds) == 0 {
+		return nil
+	}
+
+	missing, err := s.tags.GetMissingTagIds(tagIds)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return &banner.UnknownIdsError{Entity: "tag", Ids: missing}
+	}
+
+	return nil
+}
+
+// ValidateTargeting reports whether the rule is valid and, when attributes
+// are given, whether a user with them would match it.
+func (s *BannerService) ValidateTargeting(input banner.ValidateRuleInput) (banner.RuleCheck, error) {
+	err := s.checkRule(input.Rule)
+
+	var unknownIds *banner.UnknownIdsError
+	if errors.Is(err, banner.ErrInvalidRule) || errors.As(err, &unknownIds) {
+		return banner.RuleCheck{Valid: false, Error: err.Error()}, nil
+	}
+	if err != nil {
+		return banner.RuleCheck{}, err
+	}
+
+	check := banner.RuleCheck{Valid: true}
+	if input.Attributes != nil {
+		matches := matchRule(input.Rule, *input.Attributes, 0)
+		check.Matches = &matches
+	}
+
+	return check, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"banner"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	templateOpen  = "{{"
+	templateClose = "}}"
+	claimPrefix   = "user."
+)
+
+var (
+	templateVarPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+	templateClaims     = map[string]bool{"user.id": true, "user.role": true, "user.nickname": true}
+)
+
+// templatePart is either literal text or a {{name | default}} placeholder.
+type templatePart struct {
+	text        string
+	name        string
+	defaultText string
+}
+
+// parseTemplate splits a string into literal text and placeholders. Only
+// plain substitution is supported, so templates can't run code or reach
+// anything but the variables they are given.
+func parseTemplate(s string) ([]templatePart, error) {
+	var parts []templatePart
+	for {
+		start := strings.Index(s, templateOpen)
+		if start < 0 {
+			break
+		}
+
+		end := strings.Index(s[start:], templateClose)
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed %q", banner.ErrInvalidTemplate, templateOpen)
+		}
+		end += start
+
+		expr := s[start+len(templateOpen) : end]
+		name, defaultText, _ := strings.Cut(expr, "|")
+		name = strings.TrimSpace(name)
+		if !templateVarPattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: invalid variable %q", banner.ErrInvalidTemplate, name)
+		}
+		if strings.HasPrefix(name, claimPrefix) && !templateClaims[name] {
+			return nil, fmt.Errorf("%w: unknown claim %q", banner.ErrInvalidTemplate, name)
+		}
+
+		parts = append(parts, templatePart{text: s[:start]},
+			templatePart{name: name, defaultText: strings.TrimSpace(defaultText)})
+		s = s[end+len(templateClose):]
+	}
+
+	return append(parts, templatePart{text: s}), nil
+}
+
+// validateTemplates parses every string of the banner content, its
+// translations and variants so a broken template is rejected on write.
+func validateTemplates(b banner.Banner) error {
+	contents := []banner.Content{b.Content}
+	for _, content := range b.LocalizedContent {
+		contents = append(contents, content)
+	}
+	for _, v := range b.Variants {
+		contents = append(contents, v.Content)
+	}
+
+	for _, content := range contents {
+		if _, err := renderValue(map[string]interface{}(content), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templateVars merges the variables sent with the request with the claims of
+// the requesting user. Claims win so they can't be spoofed by the request.
+func templateVars(input banner.UserBannerInput, userId int, role string) map[string]string {
+	vars := make(map[string]string, len(input.Vars)+len(templateClaims))
+	for name, value := range input.Vars {
+		vars[name] = value
+	}
+	vars["user.id"] = strconv.Itoa(userId)
+	vars["user.role"] = role
+	vars["user.nickname"] = input.Nickname
+
+	return vars
+}
+
+// renderContent returns a copy of the content with every placeholder
+// replaced by its variable, its default, or nothing.
+func renderContent(content banner.Content, vars map[string]string) (banner.Content, error) {
+	rendered, err := renderValue(map[string]interface{}(content), vars)
+	if err != nil {
+		return nil, err
+	}
+	return rendered.(map[string]interface{}), nil
+}
+
+func renderValue(value interface{}, vars map[string]string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return renderString(v, vars)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			r, err := renderValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = r
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := renderValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = r
+		}
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+func renderString(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, templateOpen) {
+		return s, nil
+	}
+
+	parts, err := parseTemplate(s)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		if part.name == "" {
+			sb.WriteString(part.text)
+			continue
+		}
+
+		if value, ok := vars[part.name]; ok && value != "" {
+			sb.WriteString(value)
+		} else {
+			sb.WriteString(part.defaultText)
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"banner"
+	"hash/fnv"
+	"strconv"
+)
+
+// pickVariant deterministically assigns a user to one of the weighted variants,
+// so the same user keeps seeing the same variant of a banner.
+func pickVariant(variants []banner.Variant, bannerId, userId int) (banner.Variant, bool) {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	if total <= 0 {
+		return banner.Variant{}, false
+	}
+
+	point := int(bucket(bannerId, userId) % uint32(total))
+	for _, v := range variants {
+		if point < v.Weight {
+			return v, true
+		}
+		point -= v.Weight
+	}
+
+	return variants[len(variants)-1], true
+}
+
+func bucket(bannerId, userId int) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.Itoa(bannerId) + ":" + strconv.Itoa(userId)))
+	return h.Sum32()
+}
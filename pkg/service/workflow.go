@@ -0,0 +1,66 @@
+package service
+
+import (
+	"banner"
+	"database/sql"
+	"fmt"
+)
+
+type transition struct {
+	from []string
+	to   string
+}
+
+// transitions lists the statuses each workflow action can be taken from.
+// Editing a banner as an editor sends it back to draft, see the handler.
+var transitions = map[string]transition{
+	banner.ActionSubmit:  {from: []string{banner.StatusDraft}, to: banner.StatusInReview},
+	banner.ActionApprove: {from: []string{banner.StatusInReview}, to: banner.StatusApproved},
+	banner.ActionReject:  {from: []string{banner.StatusInReview, banner.StatusApproved}, to: banner.StatusDraft},
+	banner.ActionPublish: {from: []string{banner.StatusApproved}, to: banner.StatusPublished},
+	banner.ActionArchive: {
+		from: []string{banner.StatusDraft, banner.StatusInReview, banner.StatusApproved, banner.StatusPublished},
+		to:   banner.StatusArchived,
+	},
+}
+
+func (s *BannerService) ChangeBannerStatus(id int, action, updatedAt string, actor banner.Actor) error {
+	t, ok := transitions[action]
+	if !ok {
+		return fmt.Errorf("%w: unknown action %q", banner.ErrInvalidAction, action)
+	}
+
+	before, err := s.repo.GetBannerById(id)
+	if err != nil {
+		return err
+	}
+
+	if !containsStatus(t.from, before.Status) {
+		return fmt.Errorf("%w: can't %s a %s banner", banner.ErrInvalidAction, action, before.Status)
+	}
+
+	// The banner may have moved on since it was read.
+	err = s.repo.UpdateBannerStatus(id, action, before.Status, t.to, updatedAt, actor)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: banner status was changed concurrently", banner.ErrInvalidAction)
+	}
+	return err
+}
+
+func isValidStatus(status string) bool {
+	switch status {
+	case banner.StatusDraft, banner.StatusInReview, banner.StatusApproved, banner.StatusPublished,
+		banner.StatusArchived:
+		return true
+	}
+	return false
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package banner
+
+const (
+	RejectUnpublished  = "unpublished"
+	RejectInactive     = "inactive"
+	RejectScheduled    = StateScheduled
+	RejectExpired      = StateExpired
+	RejectTargeting    = "targeting"
+	RejectRollout      = "rollout"
+	RejectCapped       = "capped"
+	RejectRenderFailed = "render_failed"
+)
+
+type SimulateInput struct {
+	TagId           int            `json:"tag_id" binding:"required"`
+	FeatureId       int            `json:"feature_id" binding:"required"`
+	UserId          int            `json:"user_id" binding:"required"`
+	Role            string         `json:"role"`
+	UseLastRevision bool           `json:"use_last_revision"`
+	Attributes      UserAttributes `json:"attributes"`
+}
+
+// SimulatedCandidate is a banner matching the placement together with the
+// reason it would not be served, if any.
+type SimulatedCandidate struct {
+	BannerId       int    `json:"banner_id"`
+	Priority       int    `json:"priority"`
+	RejectedReason string `json:"rejected_reason,omitempty"`
+	Selected       bool   `json:"selected"`
+}
+
+type Simulation struct {
+	Candidates []SimulatedCandidate `json:"candidates"`
+	BannerId   *int                 `json:"banner_id"`
+	VariantId  int                  `json:"variant_id,omitempty"`
+}
@@ -0,0 +1,15 @@
+package banner
+
+type Tag struct {
+	Id          int    `json:"id" db:"id"`
+	Name        string `json:"name" db:"name" binding:"required"`
+	Description string `json:"description" db:"description"`
+	Owner       string `json:"owner" db:"owner"`
+	CreatedAt   string `json:"created_at" db:"created_at"`
+}
+
+type UpdateTagInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Owner       *string `json:"owner"`
+}
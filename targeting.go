@@ -0,0 +1,52 @@
+package banner
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Rule is a node of a banner targeting expression. A node holds exactly one
+// condition: a combination of child rules or a match on a user attribute.
+type Rule struct {
+	All        []Rule        `json:"all,omitempty"`
+	Any        []Rule        `json:"any,omitempty"`
+	Not        *Rule         `json:"not,omitempty"`
+	Tag        *int          `json:"tag,omitempty"`
+	AppVersion *VersionRange `json:"app_version,omitempty"`
+	Platform   []string      `json:"platform,omitempty"`
+	Country    []string      `json:"country,omitempty"`
+}
+
+// VersionRange matches versions from Min inclusive up to Max exclusive.
+// Either bound may be omitted.
+type VersionRange struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+func (r Rule) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *Rule) Scan(src interface{}) error {
+	return scanJSON(src, r)
+}
+
+// UserAttributes describe the user a banner is requested for.
+type UserAttributes struct {
+	TagIds     []int  `json:"tag_ids"`
+	AppVersion string `json:"app_version"`
+	Platform   string `json:"platform"`
+	Country    string `json:"country"`
+}
+
+type ValidateRuleInput struct {
+	Rule       Rule            `json:"rule"`
+	Attributes *UserAttributes `json:"attributes"`
+}
+
+type RuleCheck struct {
+	Valid   bool   `json:"valid"`
+	Error   string `json:"error,omitempty"`
+	Matches *bool  `json:"matches,omitempty"`
+}
@@ -6,6 +6,7 @@ import (
 	"banner/pkg/repository"
 	"banner/pkg/service"
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -18,18 +19,27 @@ import (
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
+)
+
+const (
+	testTagsCount     = 37
+	testFeaturesCount = 33
 )
 
 type BannerSuite struct {
 	suite.Suite
-	db         *sqlx.DB
-	repos      *repository.Repository
-	services   *service.Service
-	handlers   *handler.Handler
-	srv        *banner.Server
-	userToken  string
-	adminToken string
+	db            *sqlx.DB
+	repos         *repository.Repository
+	services      *service.Service
+	handlers      *handler.Handler
+	srv           *banner.Server
+	userToken     string
+	adminToken    string
+	editorToken   string
+	approverToken string
 }
 
 func (s *BannerSuite) SetupSuite() {
@@ -64,7 +74,11 @@ func (s *BannerSuite) SetupSuite() {
 	logrus.Debug("Migrations applied successfully")
 
 	s.repos = repository.NewRepository(s.db)
-	s.services = service.NewService(s.repos)
+	s.services = service.NewService(s.repos, service.NewMemoryCache(time.Minute), service.Config{
+		FallbackLocales:  []string{"en"},
+		SupportedLocales: []string{"en", "de", "fr"},
+		IdempotencyTTL:   time.Hour,
+	})
 	s.handlers = handler.NewHandler(s.services)
 
 	s.srv = new(banner.Server)
@@ -80,6 +94,7 @@ func (s *BannerSuite) SetupSuite() {
 
 func (s *BannerSuite) TearDownSuite() {
 	logrus.Println("Shutting down test server...")
+	s.services.Close()
 
 	logrus.Println("Closing test db connection...")
 	if err := s.db.Close(); err != nil {
@@ -90,9 +105,20 @@ func (s *BannerSuite) TearDownSuite() {
 func (s *BannerSuite) initData() {
 	s.register(userRegister)
 	s.register(adminRegister)
+	s.register(editorRegister)
+	s.register(approverRegister)
 
 	s.userToken = s.login(userLogin)
 	s.adminToken = s.login(adminLogin)
+	s.editorToken = s.login(editorLogin)
+	s.approverToken = s.login(approverLogin)
+
+	for i := 1; i <= testTagsCount; i++ {
+		s.createEntity("http://localhost:8080/tags", map[string]interface{}{"name": fmt.Sprintf("tag %d", i)})
+	}
+	for i := 1; i <= testFeaturesCount; i++ {
+		s.createEntity("http://localhost:8080/features", map[string]interface{}{"name": fmt.Sprintf("feature %d", i)})
+	}
 
 	s.createBanner(bannerTest1)
 	s.createBanner(bannerNotActive)
@@ -161,17 +187,100 @@ func (s *BannerSuite) login(requestBody map[string]interface{}) string {
 	return token
 }
 
-func (s *BannerSuite) createBanner(requestBody map[string]interface{}) {
+func (s *BannerSuite) doRequest(method, url, token string, requestBody interface{}) *httptest.ResponseRecorder {
+	return s.doRequestWithHeaders(method, url, token, nil, requestBody)
+}
+
+// doRequestIfMatch sends the request with an If-Match header unless etag is
+// empty.
+func (s *BannerSuite) doRequestIfMatch(method, url, token, etag string,
+	requestBody interface{}) *httptest.ResponseRecorder {
+	headers := map[string]string{}
+	if etag != "" {
+		headers["If-Match"] = etag
+	}
+	return s.doRequestWithHeaders(method, url, token, headers, requestBody)
+}
+
+func (s *BannerSuite) doRequestWithHeaders(method, url, token string, headers map[string]string,
+	requestBody interface{}) *httptest.ResponseRecorder {
+	// Raw bodies, such as import files, are sent as is.
+	jsonBody, ok := requestBody.([]byte)
+	if !ok {
+		var err error
+		if jsonBody, err = json.Marshal(requestBody); err != nil {
+			s.T().Fatalf("Failed to marshal JSON body")
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		s.T().Fatalf("Failed to create HTTP request")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(recorder)
+	req = req.WithContext(c)
+
+	s.handlers.InitRoutes().ServeHTTP(recorder, req)
+	return recorder
+}
+
+// jsonLines encodes the rows as a JSON Lines import file.
+func (s *BannerSuite) jsonLines(rows ...map[string]interface{}) []byte {
+	var buf bytes.Buffer
+	for _, row := range rows {
+		line, err := json.Marshal(row)
+		if err != nil {
+			s.T().Fatalf("Failed to marshal JSON body")
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// bannerETag reads the current ETag of the banner at url.
+func (s *BannerSuite) bannerETag(url string) string {
+	recorder := s.doRequest("GET", url, s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	return recorder.Header().Get("ETag")
+}
+
+func (s *BannerSuite) createEntity(url string, requestBody map[string]interface{}) int {
+	recorder := s.doRequest("POST", url, s.adminToken, requestBody)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var responseBody struct {
+		Id int `json:"id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &responseBody); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+	return responseBody.Id
+}
+
+func (s *BannerSuite) createBanner(requestBody map[string]interface{}) int {
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
 		s.Fail("Failed to marshal JSON body")
-		return
+		return 0
 	}
 
 	reqAdmin, err := http.NewRequest("POST", "http://localhost:8080/banner", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		s.Fail("Failed to create HTTP request")
-		return
+		return 0
 	}
 
 	//create request by admin
@@ -192,7 +301,7 @@ func (s *BannerSuite) createBanner(requestBody map[string]interface{}) {
 	reqUser, err := http.NewRequest("POST", "http://localhost:8080/banner", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		s.Fail("Failed to create HTTP request")
-		return
+		return 0
 	}
 
 	reqUser.Header.Set("Authorization", "Bearer "+s.userToken)
@@ -208,6 +317,26 @@ func (s *BannerSuite) createBanner(requestBody map[string]interface{}) {
 		s.T().FailNow()
 	}
 
+	var responseBody struct {
+		BannerId int `json:"banner_id"`
+	}
+	if err = json.Unmarshal(recorderAdmin.Body.Bytes(), &responseBody); err != nil {
+		s.Fail("Failed to parse JSON body")
+		return 0
+	}
+
+	s.publishBanner(responseBody.BannerId)
+	return responseBody.BannerId
+}
+
+// publishBanner takes a new draft banner through review so users are served it.
+func (s *BannerSuite) publishBanner(id int) {
+	for _, action := range []string{"submit", "approve", "publish"} {
+		recorder := s.doRequest("POST", fmt.Sprintf("http://localhost:8080/banner/%d/%s", id, action), s.adminToken, nil)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+	}
 }
 
 func (s *BannerSuite) TestGetInactiveBannerByUser() {
@@ -315,6 +444,1244 @@ func (s *BannerSuite) TestGetBannerByAdmin() {
 	}
 }
 
+func (s *BannerSuite) TestCreateBannerWithUnknownIds() {
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, bannerUnknownFeature)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	recorder = s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, bannerUnknownTags)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+	assert.Contains(s.T(), recorder.Body.String(), "[1000 1001]")
+}
+
+func (s *BannerSuite) TestCreateConflictingBanner() {
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, bannerConflict)
+	if !assert.Equal(s.T(), http.StatusConflict, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var responseBody struct {
+		BannerIds []int `json:"banner_ids"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &responseBody); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+	assert.Len(s.T(), responseBody.BannerIds, 1)
+}
+
+func (s *BannerSuite) TestCreateBannerWithDuplicateTags() {
+	id := s.createBanner(bannerDuplicateTags)
+
+	recorder := s.doRequest("GET", fmt.Sprintf("http://localhost:8080/banner/%d", id), s.adminToken, nil)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		var b banner.Banner
+		if err := json.Unmarshal(recorder.Body.Bytes(), &b); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		assert.Equal(s.T(), []int{37}, b.TagIds)
+	}
+}
+
+func (s *BannerSuite) TestFeaturesAndTags() {
+	id := s.createEntity("http://localhost:8080/features", map[string]interface{}{
+		"name":        "Onboarding",
+		"description": "Banners shown during onboarding",
+		"owner":       "marketing",
+	})
+	url := fmt.Sprintf("http://localhost:8080/features/%d", id)
+
+	recorder := s.doRequest("PATCH", url, s.userToken, map[string]interface{}{"name": "Renamed"})
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("PATCH", url, s.adminToken, map[string]interface{}{"owner": "growth"})
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	var feature banner.Feature
+	recorder = s.doRequest("GET", url, s.userToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &feature)
+	assert.Equal(s.T(), "Onboarding", feature.Name)
+	assert.Equal(s.T(), "growth", feature.Owner)
+
+	recorder = s.doRequest("DELETE", "http://localhost:8080/features/1", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("DELETE", "http://localhost:8080/tags/1", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("DELETE", url, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("GET", url, s.userToken, nil)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerRevisions() {
+	id := s.createBanner(bannerRevision)
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+
+	recorder := s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url), bannerRevisionUpdate)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	recorder = s.doRequest("GET", url+"/versions", s.userToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("GET", url+"/versions", s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var versions struct {
+		Data []banner.Revision `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &versions); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+	if !assert.Len(s.T(), versions.Data, 2) {
+		s.T().FailNow()
+	}
+	assert.Equal(s.T(), 2, versions.Data[0].Version)
+	assert.True(s.T(), versions.Data[0].Current)
+	assert.Equal(s.T(), "Updated revision banner", versions.Data[0].Content["title"])
+
+	recorder = s.doRequest("POST", url+"/versions/1/activate", s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	// The rolled back banner is a draft until it is published again.
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, revisionBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+	s.publishBanner(id)
+
+	var content banner.Content
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, revisionBannerSearch)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &content)
+	assert.Equal(s.T(), "Revision banner", content["title"])
+
+	// The rolled back banner is current, not the newer revision it replaced.
+	content = nil
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, lastRevisionBannerSearch)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &content)
+	assert.Equal(s.T(), "Revision banner", content["title"])
+
+	// The rollback itself is recorded as a new revision.
+	recorder = s.doRequest("GET", url+"/versions", s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	versions.Data = nil
+	if err := json.Unmarshal(recorder.Body.Bytes(), &versions); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+	if assert.Len(s.T(), versions.Data, 3) {
+		assert.Equal(s.T(), 3, versions.Data[0].Version)
+		assert.True(s.T(), versions.Data[0].Current)
+		assert.Equal(s.T(), "Revision banner", versions.Data[0].Content["title"])
+		assert.NotZero(s.T(), versions.Data[0].AuthorId)
+	}
+
+	recorder = s.doRequest("POST", url+"/versions/5/activate", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	// A revision on a tag deleted since then can't be rolled back to.
+	tagId := s.createEntity("http://localhost:8080/tags", map[string]interface{}{"name": "Revision tag"})
+	for _, tagIds := range [][]int{{tagId}, {10}} {
+		recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url),
+			map[string]interface{}{"tag_ids": tagIds})
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+	}
+
+	recorder = s.doRequest("DELETE", fmt.Sprintf("http://localhost:8080/tags/%d", tagId), s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/versions/4/activate", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+}
+
+func (s *BannerSuite) TestScheduledBanners() {
+	now := time.Now().UTC()
+	scheduled := scheduledBanner(12, now.Add(time.Hour), now.Add(2*time.Hour))
+	expired := scheduledBanner(13, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	invalid := scheduledBanner(14, now.Add(time.Hour), now)
+
+	s.createBanner(scheduled)
+	expiredId := s.createBanner(expired)
+
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, invalid)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	for _, tagId := range []int{12, 13} {
+		search := map[string]interface{}{"tag_id": tagId, "feature_id": 12}
+
+		recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, search)
+		assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+		recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.adminToken, search)
+		assert.Equal(s.T(), http.StatusOK, recorder.Code)
+	}
+
+	recorder = s.doRequest("GET", "http://localhost:8080/banner", s.adminToken, map[string]interface{}{
+		"feature_id": 12,
+		"state":      banner.StateScheduled,
+		"limit":      10,
+	})
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var banners struct {
+		Data []banner.Banner `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &banners); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+	if assert.Len(s.T(), banners.Data, 1) {
+		assert.Equal(s.T(), banner.StateScheduled, banners.Data[0].State)
+		assert.Equal(s.T(), []int{12}, banners.Data[0].TagIds)
+	}
+
+	// Sending null clears the schedule, leaving the field out keeps it.
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", expiredId)
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url),
+		map[string]interface{}{"starts_at": nil, "ends_at": nil})
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	s.publishBanner(expiredId)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken,
+		map[string]interface{}{"tag_id": 13, "feature_id": 12})
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+}
+
+func (s *BannerSuite) TestUserBannerCache() {
+	id := s.createBanner(bannerCache)
+
+	var content banner.Content
+	recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, cacheBannerSearch)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	_, err := s.db.Exec(`UPDATE banners SET content = jsonb_set(content, '{title}', '"Changed in db"') WHERE id = $1`, id)
+	if err != nil {
+		s.T().Fatalf("Failed to update banner: %s", err.Error())
+	}
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, cacheBannerSearch)
+	_ = json.Unmarshal(recorder.Body.Bytes(), &content)
+	assert.Equal(s.T(), "Cache banner", content["title"])
+
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url), bannerCacheUpdate)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	s.publishBanner(id)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, cacheBannerSearch)
+	_ = json.Unmarshal(recorder.Body.Bytes(), &content)
+	assert.Equal(s.T(), "Updated cache banner", content["title"])
+}
+
+func (s *BannerSuite) TestBannerVariants() {
+	id := s.createBanner(bannerVariants)
+
+	recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, variantsBannerSearch)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	first := recorder.Body.String()
+	variantId, _ := strconv.Atoi(recorder.Header().Get("X-Variant-Id"))
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, variantsBannerSearch)
+
+	assert.NotZero(s.T(), variantId)
+	assert.Equal(s.T(), first, recorder.Body.String())
+	assert.Equal(s.T(), strconv.Itoa(variantId), recorder.Header().Get("X-Variant-Id"))
+
+	recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/banner/%d/variants/report", id), s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var report struct {
+		Data []banner.VariantReport `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+	if assert.Len(s.T(), report.Data, 2) {
+		users := 0
+		for _, r := range report.Data {
+			if r.VariantId == variantId {
+				users = r.Users
+			}
+		}
+		assert.Equal(s.T(), 1, users)
+	}
+}
+
+func (s *BannerSuite) TestBulkDeleteBanners() {
+	id := s.createBanner(bannerBulkDelete1)
+	s.createBanner(bannerBulkDelete2)
+
+	recorder := s.doRequest("DELETE", "http://localhost:8080/banner?feature_id=30", s.userToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("DELETE", "http://localhost:8080/banner?feature_id=30", s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusAccepted, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var responseBody struct {
+		JobId int `json:"job_id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &responseBody); err != nil {
+		s.FailNow("Failed to parse JSON body")
+	}
+
+	var job banner.Job
+	for i := 0; i < 50; i++ {
+		recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/jobs/%d", responseBody.JobId), s.adminToken, nil)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &job)
+		if job.Status == banner.JobDone || job.Status == banner.JobFailed {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	assert.Equal(s.T(), banner.JobDone, job.Status)
+	assert.Equal(s.T(), 2, job.Total)
+	assert.Equal(s.T(), 2, job.Processed)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.adminToken, bulkDeleteBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	// The audit entry keeps what the deleted banner looked like.
+	recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/audit?banner_id=%d", id), s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var entries struct {
+		Data []banner.AuditEntry `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entries); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	if assert.NotEmpty(s.T(), entries.Data) {
+		assert.Equal(s.T(), banner.AuditBulkDelete, entries.Data[0].Action)
+		if change, ok := entries.Data[0].Diff["feature_id"]; assert.True(s.T(), ok) {
+			assert.Equal(s.T(), float64(30), change.Before)
+			assert.Nil(s.T(), change.After)
+		}
+	}
+}
+
+func (s *BannerSuite) TestBannerStats() {
+	id := s.createBanner(bannerStats)
+
+	for i := 0; i < 2; i++ {
+		recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, statsBannerSearch)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+	}
+
+	recorder := s.doRequest("POST", fmt.Sprintf("http://localhost:8080/banner/%d/click", id), s.userToken, nil)
+	if !assert.Equal(s.T(), http.StatusAccepted, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/banner/%d/stats", id), s.userToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	var stats struct {
+		Data []banner.DailyStats `json:"data"`
+	}
+	for i := 0; i < 30; i++ {
+		recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/banner/%d/stats", id), s.adminToken, nil)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &stats)
+		if len(stats.Data) > 0 && stats.Data[0].Clicks > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if assert.Len(s.T(), stats.Data, 1) {
+		assert.Equal(s.T(), 2, stats.Data[0].Impressions)
+		assert.Equal(s.T(), 1, stats.Data[0].UniqueUsers)
+		assert.Equal(s.T(), 1, stats.Data[0].Clicks)
+		assert.Equal(s.T(), 0.5, stats.Data[0].CTR)
+	}
+}
+
+func (s *BannerSuite) TestFrequencyCap() {
+	id := s.createBanner(bannerFrequencyCap)
+
+	for i := 0; i < 2; i++ {
+		recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, frequencyCapBannerSearch)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+	}
+
+	recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, frequencyCapBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.adminToken, frequencyCapBannerSearch)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url),
+		map[string]interface{}{"max_impressions_per_user": 0})
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	// Sending null removes the cap.
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url),
+		map[string]interface{}{"max_impressions_per_user": nil, "impression_period": nil})
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	s.publishBanner(id)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, frequencyCapBannerSearch)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerRollout() {
+	id := s.createBanner(bannerRollout)
+
+	recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, rolloutBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.adminToken, rolloutBannerSearch)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url), bannerRolloutUpdate)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	s.publishBanner(id)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, rolloutBannerSearch)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerPriority() {
+	lowId := s.createBanner(bannerLowPriority)
+	highId := s.createBanner(bannerHighPriority)
+
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, bannerHighPriority)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, priorityBannerSearch)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	assert.Equal(s.T(), strconv.Itoa(highId), recorder.Header().Get("X-Banner-Id"))
+
+	var userBanners struct {
+		Data []banner.UserBanner `json:"data"`
+	}
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banners", s.userToken, priorityBannerSearch)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &userBanners)
+	if assert.Len(s.T(), userBanners.Data, 2) {
+		assert.Equal(s.T(), highId, userBanners.Data[0].BannerId)
+		assert.Equal(s.T(), lowId, userBanners.Data[1].BannerId)
+	}
+}
+
+func (s *BannerSuite) TestFeatureContentSchema() {
+	featureId := s.createEntity("http://localhost:8080/features", featureWithSchema)
+
+	invalidBanner := map[string]interface{}{
+		"tag_ids":    []int{21},
+		"feature_id": featureId,
+		"content":    map[string]interface{}{"image_url": "https://img.url/promo.png"},
+		"is_active":  true,
+	}
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, invalidBanner)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	s.createBanner(map[string]interface{}{
+		"tag_ids":    []int{21},
+		"feature_id": featureId,
+		"content":    schemaContent,
+		"is_active":  true,
+	})
+
+	search := map[string]interface{}{"tag_id": 21, "feature_id": featureId}
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, search)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	expected, _ := json.Marshal(schemaContent)
+	assert.JSONEq(s.T(), string(expected), recorder.Body.String())
+
+	recorder = s.doRequest("PATCH", fmt.Sprintf("http://localhost:8080/features/%d", featureId), s.adminToken,
+		map[string]interface{}{"content_schema": map[string]interface{}{"type": "unknown"}})
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+}
+
+func (s *BannerSuite) TestLocalizedBanner() {
+	id := s.createBanner(bannerLocalized)
+
+	getTitle := func(lang string) (string, string) {
+		recorder := s.doRequest("GET", "http://localhost:8080/user_banner?lang="+lang, s.userToken,
+			localizedBannerSearch)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+
+		var content map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &content); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		return content["title"].(string), recorder.Header().Get("Content-Language")
+	}
+
+	title, locale := getTitle("de")
+	assert.Equal(s.T(), "Werbebanner", title)
+	assert.Equal(s.T(), "de", locale)
+
+	title, _ = getTitle("de-AT")
+	assert.Equal(s.T(), "Werbebanner", title)
+
+	title, locale = getTitle("fr")
+	assert.Equal(s.T(), "Banner", title)
+	assert.Equal(s.T(), "en", locale)
+
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+	recorder := s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url),
+		map[string]interface{}{"localized_content": map[string]interface{}{"EN": nil}})
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	s.publishBanner(id)
+
+	title, locale = getTitle("fr")
+	assert.Equal(s.T(), "Баннер", title)
+	assert.Empty(s.T(), locale)
+
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url),
+		map[string]interface{}{"localized_content": map[string]interface{}{"DE": localizedBannerPatch}})
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	s.publishBanner(id)
+
+	title, _ = getTitle("de")
+	assert.Equal(s.T(), "Neues Werbebanner", title)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/banner", s.adminToken, map[string]interface{}{
+		"feature_id": 22,
+		"limit":      10,
+	})
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var banners struct {
+		Data []banner.Banner `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &banners); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	if assert.Len(s.T(), banners.Data, 1) {
+		assert.Equal(s.T(), []string{"en", "fr"}, banners.Data[0].MissingLocales)
+	}
+}
+
+func (s *BannerSuite) TestBannerTemplate() {
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, bannerBrokenTemplate)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	s.createBanner(bannerTemplate)
+
+	getContent := func(search map[string]interface{}) map[string]interface{} {
+		recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, search)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+
+		var content map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &content); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		return content
+	}
+
+	content := getContent(map[string]interface{}{
+		"tag_id":     23,
+		"feature_id": 23,
+		"vars":       map[string]string{"discount": "25"},
+	})
+	assert.Equal(s.T(), "Hi, user!", content["title"])
+	assert.Equal(s.T(), "Save 25% today", content["text"])
+
+	content = getContent(map[string]interface{}{"tag_id": 23, "feature_id": 23})
+	assert.Equal(s.T(), "Save 10% today", content["text"])
+}
+
+func (s *BannerSuite) TestBannerTargeting() {
+	s.createBanner(bannerTargeting)
+
+	search := func(attributes map[string]interface{}) int {
+		recorder := s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, map[string]interface{}{
+			"tag_id":     25,
+			"feature_id": 25,
+			"attributes": attributes,
+		})
+		return recorder.Code
+	}
+
+	assert.Equal(s.T(), http.StatusOK, search(map[string]interface{}{
+		"platform": "iOS", "app_version": "2.1.3", "country": "US",
+	}))
+	assert.Equal(s.T(), http.StatusNotFound, search(map[string]interface{}{
+		"platform": "android", "app_version": "2.1.3", "country": "US",
+	}))
+	assert.Equal(s.T(), http.StatusNotFound, search(map[string]interface{}{
+		"platform": "ios", "app_version": "1.9", "country": "US",
+	}))
+	assert.Equal(s.T(), http.StatusNotFound, search(map[string]interface{}{
+		"platform": "ios", "app_version": "2.1.3", "country": "RU",
+	}))
+
+	var check banner.RuleCheck
+	validate := func(body map[string]interface{}) {
+		recorder := s.doRequest("POST", "http://localhost:8080/banner/targeting/validate", s.adminToken, body)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+		check = banner.RuleCheck{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &check); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+	}
+
+	validate(map[string]interface{}{
+		"rule": map[string]interface{}{"platform": []string{"ios"}, "country": []string{"US"}},
+	})
+	assert.False(s.T(), check.Valid)
+	assert.NotEmpty(s.T(), check.Error)
+
+	validate(map[string]interface{}{
+		"rule":       targetingRule,
+		"attributes": map[string]interface{}{"platform": "ios", "app_version": "3.0", "country": "DE"},
+	})
+	assert.True(s.T(), check.Valid)
+	if assert.NotNil(s.T(), check.Matches) {
+		assert.True(s.T(), *check.Matches)
+	}
+
+	recorder := s.doRequest("POST", "http://localhost:8080/banner/targeting/validate", s.userToken,
+		map[string]interface{}{"rule": targetingRule})
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	// A tag only referenced by a targeting rule is still in use.
+	tagId := s.createEntity("http://localhost:8080/tags", map[string]interface{}{"name": "Targeted users"})
+	s.createBanner(tagTargetedBanner(tagId))
+
+	recorder = s.doRequest("DELETE", fmt.Sprintf("http://localhost:8080/tags/%d", tagId), s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerSimulation() {
+	var ids []int
+	for _, b := range simulationBanners {
+		ids = append(ids, s.createBanner(b))
+	}
+
+	simulate := func(role string) banner.Simulation {
+		recorder := s.doRequest("POST", "http://localhost:8080/banner/simulate", s.adminToken, map[string]interface{}{
+			"tag_id":            26,
+			"feature_id":        26,
+			"user_id":           42,
+			"role":              role,
+			"use_last_revision": true,
+		})
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+
+		var simulation banner.Simulation
+		if err := json.Unmarshal(recorder.Body.Bytes(), &simulation); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		return simulation
+	}
+
+	simulation := simulate("user")
+	if assert.Len(s.T(), simulation.Candidates, 3) {
+		assert.Equal(s.T(), banner.RejectInactive, simulation.Candidates[0].RejectedReason)
+		assert.Equal(s.T(), banner.RejectRollout, simulation.Candidates[1].RejectedReason)
+		assert.Empty(s.T(), simulation.Candidates[2].RejectedReason)
+		assert.True(s.T(), simulation.Candidates[2].Selected)
+	}
+	if assert.NotNil(s.T(), simulation.BannerId) {
+		assert.Equal(s.T(), ids[2], *simulation.BannerId)
+	}
+
+	simulation = simulate("admin")
+	if assert.NotNil(s.T(), simulation.BannerId) {
+		assert.Equal(s.T(), ids[0], *simulation.BannerId)
+	}
+
+	// A template saved before templates were validated can't be served.
+	_, err := s.db.Exec(`UPDATE banners SET content = '{"title": "{{ unclosed"}' WHERE id = $1`, ids[2])
+	if !assert.NoError(s.T(), err) {
+		s.T().FailNow()
+	}
+
+	simulation = simulate("user")
+	if assert.Len(s.T(), simulation.Candidates, 3) {
+		assert.Equal(s.T(), banner.RejectRenderFailed, simulation.Candidates[2].RejectedReason)
+	}
+	assert.Nil(s.T(), simulation.BannerId)
+
+	recorder := s.doRequest("POST", "http://localhost:8080/banner/simulate", s.userToken, map[string]interface{}{
+		"tag_id": 26, "feature_id": 26, "user_id": 42,
+	})
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+}
+
+func (s *BannerSuite) TestAuditLog() {
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.adminToken, bannerAudit)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		s.T().FailNow()
+	}
+	requestId := recorder.Header().Get("X-Request-Id")
+	assert.NotEmpty(s.T(), requestId)
+
+	var created struct {
+		BannerId int `json:"banner_id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", created.BannerId)
+
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url), bannerAuditUpdate)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequestIfMatch("DELETE", url, s.adminToken, s.bannerETag(url), nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/audit?banner_id=%d", created.BannerId),
+		s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var entries struct {
+		Data []banner.AuditEntry `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entries); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	if !assert.Len(s.T(), entries.Data, 3) {
+		s.T().FailNow()
+	}
+
+	assert.Equal(s.T(), banner.AuditDelete, entries.Data[0].Action)
+	assert.Equal(s.T(), banner.AuditUpdate, entries.Data[1].Action)
+	assert.Equal(s.T(), banner.AuditCreate, entries.Data[2].Action)
+	assert.Equal(s.T(), requestId, entries.Data[2].RequestId)
+
+	change, ok := entries.Data[1].Diff["content"]
+	if assert.True(s.T(), ok) {
+		assert.Equal(s.T(), "Audit banner", change.Before.(map[string]interface{})["title"])
+		assert.Equal(s.T(), "Audited banner", change.After.(map[string]interface{})["title"])
+	}
+	assert.NotContains(s.T(), entries.Data[1].Diff, "feature_id")
+
+	recorder = s.doRequest("GET", "http://localhost:8080/audit", s.userToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+}
+
+func (s *BannerSuite) TestSoftDeleteBanner() {
+	id := s.createBanner(bannerSoftDelete)
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+
+	etag := s.bannerETag(url)
+	recorder := s.doRequestIfMatch("DELETE", url, s.adminToken, etag, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, softDeleteBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	recorder = s.doRequestIfMatch("DELETE", url, s.adminToken, etag, nil)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	replacementId := s.createBanner(bannerSoftDeleteReplacement)
+
+	recorder = s.doRequest("POST", url+"/restore", s.adminToken, nil)
+	if assert.Equal(s.T(), http.StatusConflict, recorder.Code) {
+		var conflict struct {
+			BannerIds []int `json:"banner_ids"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &conflict); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		assert.Equal(s.T(), []int{replacementId}, conflict.BannerIds)
+	}
+
+	replacementUrl := fmt.Sprintf("http://localhost:8080/banner/%d", replacementId)
+	recorder = s.doRequestIfMatch("DELETE", replacementUrl, s.adminToken, s.bannerETag(replacementUrl), nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/restore", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, softDeleteBannerSearch)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		var content map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &content); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		assert.Equal(s.T(), "Deleted banner", content["title"])
+	}
+
+	recorder = s.doRequest("POST", url+"/restore", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	purged, err := s.repos.PurgeDeletedBanners(time.Now().Add(time.Minute))
+	if assert.NoError(s.T(), err) {
+		assert.Contains(s.T(), purged, replacementId)
+	}
+
+	recorder = s.doRequest("POST", replacementUrl+"/restore", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	// A banner whose tag was deleted while it was in the trash can't come back.
+	tagId := s.createEntity("http://localhost:8080/tags", map[string]interface{}{"name": "Deleted tag"})
+	orphanUrl := fmt.Sprintf("http://localhost:8080/banner/%d", s.createBanner(softDeletedTagBanner(tagId)))
+	recorder = s.doRequestIfMatch("DELETE", orphanUrl, s.adminToken, s.bannerETag(orphanUrl), nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("DELETE", fmt.Sprintf("http://localhost:8080/tags/%d", tagId), s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("POST", orphanUrl+"/restore", s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerWorkflow() {
+	recorder := s.doRequest("POST", "http://localhost:8080/banner", s.editorToken, bannerWorkflow)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		return
+	}
+
+	var created map[string]int
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", created["banner_id"])
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, workflowBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/approve", s.approverToken, nil)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/submit", s.userToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/submit", s.editorToken, nil)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/approve", s.editorToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/approve", s.approverToken, nil)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, workflowBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/publish", s.approverToken, nil)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, workflowBannerSearch)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	// An editor can't take the published banner off the air by editing it.
+	recorder = s.doRequestIfMatch("PATCH", url, s.editorToken, s.bannerETag(url), map[string]interface{}{
+		"content": map[string]string{"title": "Edited banner"},
+	})
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, workflowBannerSearch)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	// An admin edit is reviewed again too, so the banner is off the air until
+	// it is published again.
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, s.bannerETag(url), map[string]interface{}{
+		"content": map[string]string{"title": "Edited banner"},
+	})
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequest("GET", "http://localhost:8080/user_banner", s.userToken, workflowBannerSearch)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	recorder = s.doRequest("POST", url+"/submit", s.editorToken, nil)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+	for _, action := range []string{"approve", "publish"} {
+		recorder = s.doRequest("POST", url+"/"+action, s.approverToken, nil)
+		assert.Equal(s.T(), http.StatusOK, recorder.Code)
+	}
+
+	recorder = s.doRequest("GET", "http://localhost:8080/banner", s.approverToken, map[string]interface{}{
+		"feature_id": 29,
+		"status":     "published",
+		"limit":      10,
+	})
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		var response struct {
+			Data []banner.Banner `json:"data"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		if assert.Len(s.T(), response.Data, 1) {
+			assert.Equal(s.T(), "Edited banner", response.Data[0].Content["title"])
+		}
+	}
+}
+
+func (s *BannerSuite) TestBannerETag() {
+	id := s.createBanner(bannerETag)
+	url := fmt.Sprintf("http://localhost:8080/banner/%d", id)
+
+	// Created, submitted, approved and published.
+	etag := s.bannerETag(url)
+	assert.Equal(s.T(), `"4"`, etag)
+
+	recorder := s.doRequest("PATCH", url, s.adminToken, bannerETagUpdate)
+	assert.Equal(s.T(), http.StatusPreconditionRequired, recorder.Code)
+
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, etag, bannerETagUpdate)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		assert.Equal(s.T(), `"5"`, recorder.Header().Get("ETag"))
+	}
+
+	// A second writer that read the banner before the update is rejected.
+	recorder = s.doRequestIfMatch("PATCH", url, s.adminToken, etag, bannerETagUpdate)
+	assert.Equal(s.T(), http.StatusPreconditionFailed, recorder.Code)
+
+	recorder = s.doRequestIfMatch("DELETE", url, s.adminToken, etag, nil)
+	assert.Equal(s.T(), http.StatusPreconditionFailed, recorder.Code)
+
+	recorder = s.doRequest("DELETE", url, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusPreconditionRequired, recorder.Code)
+
+	recorder = s.doRequestIfMatch("DELETE", url, s.adminToken, s.bannerETag(url), nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+}
+
+func (s *BannerSuite) TestIdempotencyKey() {
+	headers := map[string]string{"Idempotency-Key": "create-idempotent-banner"}
+	url := "http://localhost:8080/banner"
+
+	recorder := s.doRequestWithHeaders("POST", url, s.adminToken, headers, bannerIdempotent)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		s.T().FailNow()
+	}
+	created := recorder.Body.String()
+
+	recorder = s.doRequestWithHeaders("POST", url, s.adminToken, headers, bannerIdempotent)
+	assert.Equal(s.T(), http.StatusCreated, recorder.Code)
+	assert.Equal(s.T(), created, recorder.Body.String())
+	assert.Equal(s.T(), "true", recorder.Header().Get("Idempotent-Replayed"))
+
+	// Keys are per user, so another user's key doesn't replay this response.
+	recorder = s.doRequestWithHeaders("POST", url, s.editorToken, headers, bannerIdempotent)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequestWithHeaders("POST", url, s.adminToken, headers, bannerIdempotentChanged)
+	assert.Equal(s.T(), http.StatusUnprocessableEntity, recorder.Code)
+
+	recorder = s.doRequest("POST", url, s.adminToken, bannerIdempotent)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerCloneAndTemplates() {
+	id := s.createBanner(bannerClone)
+	cloneUrl := fmt.Sprintf("http://localhost:8080/banner/%d/clone", id)
+
+	// A clone left at the original's place conflicts with it.
+	recorder := s.doRequest("POST", cloneUrl, s.adminToken, map[string]interface{}{})
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("POST", cloneUrl, s.userToken, cloneOverrides)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("POST", cloneUrl, s.adminToken, cloneOverrides)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		s.T().FailNow()
+	}
+
+	var created struct {
+		BannerId int `json:"banner_id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+
+	recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/banner/%d", created.BannerId),
+		s.adminToken, nil)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		var clone banner.Banner
+		if err := json.Unmarshal(recorder.Body.Bytes(), &clone); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		assert.Equal(s.T(), []int{33}, clone.TagIds)
+		assert.Equal(s.T(), "Cloned banner", clone.Content["title"])
+	}
+
+	recorder = s.doRequest("POST", "http://localhost:8080/templates", s.editorToken, savedTemplate)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	templateId := s.createEntity("http://localhost:8080/templates", savedTemplate)
+	templateUrl := fmt.Sprintf("http://localhost:8080/templates/%d", templateId)
+
+	recorder = s.doRequest("POST", "http://localhost:8080/templates", s.adminToken, savedTemplate)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("PATCH", templateUrl, s.adminToken, savedTemplateUpdate)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	for _, url := range []string{templateUrl, "http://localhost:8080/templates"} {
+		recorder = s.doRequest("GET", url, s.userToken, nil)
+		assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+	}
+
+	recorder = s.doRequest("GET", templateUrl, s.editorToken, nil)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		var template banner.BannerTemplate
+		if err := json.Unmarshal(recorder.Body.Bytes(), &template); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		assert.Equal(s.T(), "Spring promo", template.Description)
+	}
+
+	recorder = s.doRequest("POST", templateUrl+"/banners", s.editorToken, templateInstance)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		s.T().FailNow()
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+
+	recorder = s.doRequest("GET", fmt.Sprintf("http://localhost:8080/banner/%d", created.BannerId),
+		s.adminToken, nil)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		var instance banner.Banner
+		if err := json.Unmarshal(recorder.Body.Bytes(), &instance); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		assert.Equal(s.T(), "Promo banner", instance.Content["title"])
+		assert.Equal(s.T(), 1, instance.Priority)
+		assert.Equal(s.T(), banner.StatusDraft, instance.Status)
+	}
+
+	recorder = s.doRequest("DELETE", templateUrl, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("GET", templateUrl, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNotFound, recorder.Code)
+
+	// A feature used only by a template is still in use.
+	featureId := s.createEntity("http://localhost:8080/features", map[string]interface{}{"name": "Template feature"})
+	featureUrl := fmt.Sprintf("http://localhost:8080/features/%d", featureId)
+	templateUrl = fmt.Sprintf("http://localhost:8080/templates/%d", s.createEntity("http://localhost:8080/templates",
+		map[string]interface{}{
+			"name":       "Feature template",
+			"feature_id": featureId,
+			"content":    map[string]string{"title": "Feature template banner"},
+		}))
+
+	recorder = s.doRequest("DELETE", featureUrl, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusConflict, recorder.Code)
+
+	recorder = s.doRequest("DELETE", templateUrl, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+
+	recorder = s.doRequest("DELETE", featureUrl, s.adminToken, nil)
+	assert.Equal(s.T(), http.StatusNoContent, recorder.Code)
+}
+
+func (s *BannerSuite) TestBannerImportExport() {
+	importUrl := "http://localhost:8080/banner/import"
+	exportUrl := "http://localhost:8080/banner/export?tag_id=34"
+	importFile := s.jsonLines(bannerImportFirst, bannerImportSecond)
+
+	recorder := s.doRequest("POST", importUrl, s.editorToken, importFile)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	// Every row is checked and nothing is created when any of them is invalid.
+	recorder = s.doRequest("POST", importUrl, s.adminToken,
+		s.jsonLines(bannerImportFirst, bannerImportUnknownFeature, bannerImportFirst))
+	if assert.Equal(s.T(), http.StatusUnprocessableEntity, recorder.Code) {
+		var result banner.ImportResult
+		if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		if assert.Len(s.T(), result.Errors, 2) {
+			assert.Equal(s.T(), 2, result.Errors[0].Line)
+			assert.Equal(s.T(), 3, result.Errors[1].Line)
+		}
+	}
+
+	recorder = s.doRequest("POST", importUrl+"?dry_run=true", s.adminToken, importFile)
+	assert.Equal(s.T(), http.StatusOK, recorder.Code)
+
+	recorder = s.doRequest("GET", exportUrl, s.adminToken, nil)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		assert.Empty(s.T(), recorder.Body.String())
+	}
+
+	recorder = s.doRequest("POST", importUrl, s.adminToken, importFile)
+	if !assert.Equal(s.T(), http.StatusCreated, recorder.Code) {
+		s.T().FailNow()
+	}
+	var result banner.ImportResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	assert.Len(s.T(), result.BannerIds, 2)
+
+	recorder = s.doRequest("GET", exportUrl, s.userToken, nil)
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+
+	recorder = s.doRequest("GET", exportUrl, s.adminToken, nil)
+	if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		s.T().FailNow()
+	}
+	exported := recorder.Body.Bytes()
+
+	var exportedBanner banner.Banner
+	if err := json.Unmarshal(exported, &exportedBanner); err != nil {
+		s.T().Fatalf("Failed to parse JSON body")
+	}
+	assert.Equal(s.T(), result.BannerIds[0], exportedBanner.Id)
+	assert.Equal(s.T(), "First imported banner", exportedBanner.Content["title"])
+	assert.Equal(s.T(), banner.StatusDraft, exportedBanner.Status)
+
+	// The exported banner occupies its place, so importing it again conflicts.
+	recorder = s.doRequest("POST", importUrl, s.adminToken, exported)
+	assert.Equal(s.T(), http.StatusUnprocessableEntity, recorder.Code)
+
+	recorder = s.doRequest("GET", exportUrl+"&format=csv", s.adminToken, nil)
+	if assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+		records, err := csv.NewReader(recorder.Body).ReadAll()
+		if err != nil {
+			s.T().Fatalf("Failed to parse CSV body")
+		}
+		if assert.Len(s.T(), records, 2) {
+			assert.Equal(s.T(), "tag_ids", records[0][1])
+			assert.Equal(s.T(), "[34]", records[1][1])
+		}
+	}
+}
+
+func (s *BannerSuite) TestBannerFilters() {
+	for _, b := range filteredBanners {
+		s.createBanner(b)
+	}
+
+	type getAllBannersResponse struct {
+		Data       []banner.Banner `json:"data"`
+		NextCursor string          `json:"next_cursor"`
+		Total      int             `json:"total"`
+	}
+	getBanners := func(filter map[string]interface{}) getAllBannersResponse {
+		recorder := s.doRequest("GET", "http://localhost:8080/banner", s.adminToken, filter)
+		if !assert.Equal(s.T(), http.StatusOK, recorder.Code) {
+			s.T().FailNow()
+		}
+
+		var response getAllBannersResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			s.T().Fatalf("Failed to parse JSON body")
+		}
+		return response
+	}
+
+	page := getBanners(map[string]interface{}{"tag_id": 36, "feature_id": 32, "sort": "-priority", "limit": 2})
+	assert.Equal(s.T(), 3, page.Total)
+	if assert.Len(s.T(), page.Data, 2) {
+		assert.Equal(s.T(), 3, page.Data[0].Priority)
+		assert.Equal(s.T(), 2, page.Data[1].Priority)
+	}
+	if !assert.NotEmpty(s.T(), page.NextCursor) {
+		s.T().FailNow()
+	}
+
+	next := getBanners(map[string]interface{}{
+		"tag_id":     36,
+		"feature_id": 32,
+		"sort":       "-priority",
+		"limit":      2,
+		"cursor":     page.NextCursor,
+	})
+	if assert.Len(s.T(), next.Data, 1) {
+		assert.Equal(s.T(), 1, next.Data[0].Priority)
+	}
+	assert.Empty(s.T(), next.NextCursor)
+
+	// A cursor only continues the order it was made for.
+	recorder := s.doRequest("GET", "http://localhost:8080/banner", s.adminToken, map[string]interface{}{
+		"feature_id": 32,
+		"sort":       "created_at",
+		"cursor":     page.NextCursor,
+	})
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	// Offset paging was replaced by cursors.
+	recorder = s.doRequest("GET", "http://localhost:8080/banner", s.adminToken, map[string]interface{}{
+		"feature_id": 32,
+		"offset":     2,
+	})
+	assert.Equal(s.T(), http.StatusBadRequest, recorder.Code)
+
+	// Filters are combined, so a tag of other banners matches nothing here.
+	assert.Equal(s.T(), 0, getBanners(map[string]interface{}{"tag_id": 12, "feature_id": 32}).Total)
+	assert.Equal(s.T(), 1, getBanners(map[string]interface{}{"feature_id": 32, "is_active": false}).Total)
+	assert.Equal(s.T(), 2, getBanners(map[string]interface{}{"feature_id": 32, "search": "SALE"}).Total)
+	assert.Equal(s.T(), 1, getBanners(map[string]interface{}{"feature_id": 32, "search": "angebot"}).Total)
+	assert.Equal(s.T(), 0, getBanners(map[string]interface{}{
+		"feature_id":   32,
+		"created_from": time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+	}).Total)
+}
+
 func TestBannerSuite(t *testing.T) {
 	suite.Run(t, new(BannerSuite))
 }
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"banner/pkg/service"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testCache(t *testing.T, cache service.Cache) {
+	_, err := cache.Get("user_banner:1:1:user")
+	assert.Equal(t, service.ErrCacheMiss, err)
+
+	assert.NoError(t, cache.Set("user_banner:1:1:user", []byte("first")))
+	assert.NoError(t, cache.Set("user_banner:2:1:admin", []byte("second")))
+	assert.NoError(t, cache.Set("other:1", []byte("other")))
+
+	value, err := cache.Get("user_banner:1:1:user")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("first"), value)
+
+	assert.NoError(t, cache.Clear("user_banner:"))
+
+	_, err = cache.Get("user_banner:1:1:user")
+	assert.Equal(t, service.ErrCacheMiss, err)
+	_, err = cache.Get("user_banner:2:1:admin")
+	assert.Equal(t, service.ErrCacheMiss, err)
+
+	value, err = cache.Get("other:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("other"), value)
+}
+
+func TestMemoryCache(t *testing.T) {
+	testCache(t, service.NewMemoryCache(time.Minute))
+
+	cache := service.NewMemoryCache(time.Millisecond)
+	assert.NoError(t, cache.Set("key", []byte("value")))
+	time.Sleep(5 * time.Millisecond)
+	_, err := cache.Get("key")
+	assert.Equal(t, service.ErrCacheMiss, err)
+}
+
+func TestRedisCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testCache(t, service.NewRedisCache(client, time.Minute))
+
+	cache := service.NewRedisCache(client, time.Second)
+	assert.NoError(t, cache.Set("key", []byte("value")))
+	mr.FastForward(2 * time.Second)
+	_, err := cache.Get("key")
+	assert.Equal(t, service.ErrCacheMiss, err)
+}
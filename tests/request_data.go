@@ -1,5 +1,7 @@
 package tests
 
+import "time"
+
 var (
 	userRegister = map[string]interface{}{
 		"NickName":        "user",
@@ -17,6 +19,22 @@ var (
 		"Role":            "admin",
 	}
 
+	editorRegister = map[string]interface{}{
+		"NickName":        "editor",
+		"Email":           "editor@gmail.com",
+		"Password":        "password",
+		"PasswordConfirm": "password",
+		"Role":            "editor",
+	}
+
+	approverRegister = map[string]interface{}{
+		"NickName":        "approver",
+		"Email":           "approver@gmail.com",
+		"Password":        "password",
+		"PasswordConfirm": "password",
+		"Role":            "approver",
+	}
+
 	userLogin = map[string]interface{}{
 		"NickName": "user",
 		"Password": "password",
@@ -27,8 +45,18 @@ var (
 		"Password": "password",
 	}
 
+	editorLogin = map[string]interface{}{
+		"NickName": "editor",
+		"Password": "password",
+	}
+
+	approverLogin = map[string]interface{}{
+		"NickName": "approver",
+		"Password": "password",
+	}
+
 	bannerTest1 = map[string]interface{}{
-		"tag_ids":    []int{0, 1, 2},
+		"tag_ids":    []int{3, 1, 2},
 		"feature_id": 1,
 		"content": map[string]string{
 			"title": "Test banner1",
@@ -39,7 +67,7 @@ var (
 	}
 
 	bannerNotActive = map[string]interface{}{
-		"tag_ids":    []int{0, 2},
+		"tag_ids":    []int{3, 2},
 		"feature_id": 2,
 		"content": map[string]string{
 			"title": "Test banner2",
@@ -50,7 +78,7 @@ var (
 	}
 
 	bannerTest2 = map[string]interface{}{
-		"tag_ids":    []int{0},
+		"tag_ids":    []int{3},
 		"feature_id": 3,
 		"content": map[string]string{
 			"title": "Test banner3",
@@ -60,13 +88,614 @@ var (
 		"is_active": true,
 	}
 
+	bannerUnknownFeature = map[string]interface{}{
+		"tag_ids":    []int{1},
+		"feature_id": 1000,
+		"content": map[string]string{
+			"title": "Unknown feature banner",
+			"text":  "Unknown feature text",
+			"url":   "https://unknown.url",
+		},
+		"is_active": true,
+	}
+
+	bannerUnknownTags = map[string]interface{}{
+		"tag_ids":    []int{1, 1000, 1001},
+		"feature_id": 1,
+		"content": map[string]string{
+			"title": "Unknown tags banner",
+			"text":  "Unknown tags text",
+			"url":   "https://unknown.url",
+		},
+		"is_active": true,
+	}
+
+	bannerConflict = map[string]interface{}{
+		"tag_ids":    []int{5, 2},
+		"feature_id": 1,
+		"content": map[string]string{
+			"title": "Conflicting banner",
+			"text":  "Conflicting text",
+			"url":   "https://conflict.url",
+		},
+		"is_active": true,
+	}
+
+	bannerDuplicateTags = map[string]interface{}{
+		"tag_ids":    []int{37, 37},
+		"feature_id": 33,
+		"content": map[string]string{
+			"title": "Duplicate tags banner",
+			"text":  "Duplicate tags text",
+			"url":   "https://duplicate.url",
+		},
+		"is_active": true,
+	}
+
+	bannerVariants = map[string]interface{}{
+		"tag_ids":    []int{15},
+		"feature_id": 15,
+		"content": map[string]string{
+			"title": "Variants banner",
+			"text":  "Variants text",
+			"url":   "https://variants.url",
+		},
+		"is_active": true,
+		"variants": []map[string]interface{}{
+			{
+				"content": map[string]string{"title": "Headline A", "text": "Variant A", "url": "https://a.url"},
+				"weight":  1,
+			},
+			{
+				"content": map[string]string{"title": "Headline B", "text": "Variant B", "url": "https://b.url"},
+				"weight":  1,
+			},
+		},
+	}
+
+	variantsBannerSearch = map[string]interface{}{
+		"tag_id":     15,
+		"feature_id": 15,
+	}
+
+	bannerStats = map[string]interface{}{
+		"tag_ids":    []int{16},
+		"feature_id": 16,
+		"content": map[string]string{
+			"title": "Stats banner",
+			"text":  "Stats text",
+			"url":   "https://stats.url",
+		},
+		"is_active": true,
+	}
+
+	statsBannerSearch = map[string]interface{}{
+		"tag_id":     16,
+		"feature_id": 16,
+	}
+
+	bannerFrequencyCap = map[string]interface{}{
+		"tag_ids":    []int{17},
+		"feature_id": 17,
+		"content": map[string]string{
+			"title": "Capped banner",
+			"text":  "Capped text",
+			"url":   "https://capped.url",
+		},
+		"is_active":                true,
+		"max_impressions_per_user": 2,
+		"impression_period":        3600,
+	}
+
+	frequencyCapBannerSearch = map[string]interface{}{
+		"tag_id":     17,
+		"feature_id": 17,
+	}
+
+	bannerRollout = map[string]interface{}{
+		"tag_ids":    []int{18},
+		"feature_id": 18,
+		"content": map[string]string{
+			"title": "Rollout banner",
+			"text":  "Rollout text",
+			"url":   "https://rollout.url",
+		},
+		"is_active":       true,
+		"rollout_percent": 0,
+	}
+
+	bannerRolloutUpdate = map[string]interface{}{
+		"rollout_percent": 100,
+	}
+
+	rolloutBannerSearch = map[string]interface{}{
+		"tag_id":     18,
+		"feature_id": 18,
+	}
+
+	bannerLowPriority = map[string]interface{}{
+		"tag_ids":    []int{19},
+		"feature_id": 19,
+		"content": map[string]string{
+			"title": "Low priority banner",
+			"text":  "Low priority text",
+			"url":   "https://low.url",
+		},
+		"is_active": true,
+		"priority":  1,
+	}
+
+	bannerHighPriority = map[string]interface{}{
+		"tag_ids":    []int{19},
+		"feature_id": 19,
+		"content": map[string]string{
+			"title": "High priority banner",
+			"text":  "High priority text",
+			"url":   "https://high.url",
+		},
+		"is_active": true,
+		"priority":  5,
+	}
+
+	priorityBannerSearch = map[string]interface{}{
+		"tag_id":     19,
+		"feature_id": 19,
+	}
+
+	featureWithSchema = map[string]interface{}{
+		"name": "mobile promo",
+		"content_schema": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"image_url", "cta"},
+			"properties": map[string]interface{}{
+				"image_url": map[string]interface{}{"type": "string"},
+				"cta": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"label"},
+					"properties": map[string]interface{}{
+						"label": map[string]interface{}{"type": "string"},
+						"color": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+
+	schemaContent = map[string]interface{}{
+		"image_url": "https://img.url/promo.png",
+		"cta": map[string]interface{}{
+			"label": "Buy",
+			"color": "#ff0000",
+		},
+	}
+
+	bannerLocalized = map[string]interface{}{
+		"tag_ids":    []int{22},
+		"feature_id": 22,
+		"content": map[string]string{
+			"title": "Баннер",
+			"text":  "Текст баннера",
+			"url":   "https://ru.url",
+		},
+		"localized_content": map[string]interface{}{
+			"en": map[string]string{
+				"title": "Banner",
+				"text":  "Banner text",
+				"url":   "https://en.url",
+			},
+			"DE": map[string]string{
+				"title": "Werbebanner",
+				"text":  "Bannertext",
+				"url":   "https://de.url",
+			},
+		},
+		"is_active": true,
+	}
+
+	localizedBannerPatch = map[string]string{
+		"title": "Neues Werbebanner",
+		"text":  "Neuer Bannertext",
+		"url":   "https://de.url",
+	}
+
+	localizedBannerSearch = map[string]interface{}{
+		"tag_id":     22,
+		"feature_id": 22,
+	}
+
+	bannerTemplate = map[string]interface{}{
+		"tag_ids":    []int{23},
+		"feature_id": 23,
+		"content": map[string]string{
+			"title": "Hi, {{ user.nickname | friend }}!",
+			"text":  "Save {{discount|10}}% today",
+			"url":   "https://template.url",
+		},
+		"is_active": true,
+	}
+
+	bannerBrokenTemplate = map[string]interface{}{
+		"tag_ids":    []int{24},
+		"feature_id": 24,
+		"content": map[string]string{
+			"title": "Hi, {{ user.nickname",
+			"text":  "Broken template",
+			"url":   "https://template.url",
+		},
+		"is_active": true,
+	}
+
+	targetingRule = map[string]interface{}{
+		"all": []interface{}{
+			map[string]interface{}{"platform": []string{"ios"}},
+			map[string]interface{}{"app_version": map[string]string{"min": "2.0"}},
+			map[string]interface{}{"not": map[string]interface{}{"country": []string{"RU"}}},
+		},
+	}
+
+	bannerTargeting = map[string]interface{}{
+		"tag_ids":    []int{25},
+		"feature_id": 25,
+		"content": map[string]string{
+			"title": "Targeted banner",
+			"text":  "Targeted text",
+			"url":   "https://targeted.url",
+		},
+		"targeting": targetingRule,
+		"is_active": true,
+	}
+
+	simulationBanners = []map[string]interface{}{
+		{
+			"tag_ids":    []int{26},
+			"feature_id": 26,
+			"content":    map[string]string{"title": "Inactive banner"},
+			"is_active":  false,
+			"priority":   3,
+		},
+		{
+			"tag_ids":         []int{26},
+			"feature_id":      26,
+			"content":         map[string]string{"title": "Rollout banner"},
+			"is_active":       true,
+			"rollout_percent": 0,
+			"priority":        2,
+		},
+		{
+			"tag_ids":    []int{26},
+			"feature_id": 26,
+			"content":    map[string]string{"title": "Served banner"},
+			"is_active":  true,
+			"priority":   1,
+		},
+	}
+
+	bannerAudit = map[string]interface{}{
+		"tag_ids":    []int{27},
+		"feature_id": 27,
+		"content": map[string]string{
+			"title": "Audit banner",
+			"text":  "Audit text",
+			"url":   "https://audit.url",
+		},
+		"is_active": true,
+	}
+
+	bannerAuditUpdate = map[string]interface{}{
+		"content": map[string]string{
+			"title": "Audited banner",
+		},
+		"is_active": true,
+	}
+
+	bannerSoftDelete = map[string]interface{}{
+		"tag_ids":    []int{28},
+		"feature_id": 28,
+		"content": map[string]string{
+			"title": "Deleted banner",
+			"text":  "Deleted text",
+			"url":   "https://deleted.url",
+		},
+		"is_active": true,
+	}
+
+	bannerSoftDeleteReplacement = map[string]interface{}{
+		"tag_ids":    []int{28},
+		"feature_id": 28,
+		"content": map[string]string{
+			"title": "Replacement banner",
+			"text":  "Replacement text",
+			"url":   "https://replacement.url",
+		},
+		"is_active": true,
+	}
+
+	bannerWorkflow = map[string]interface{}{
+		"tag_ids":    []int{29},
+		"feature_id": 29,
+		"content": map[string]string{
+			"title": "Reviewed banner",
+			"text":  "Reviewed text",
+			"url":   "https://reviewed.url",
+		},
+		"is_active": true,
+	}
+
+	workflowBannerSearch = map[string]interface{}{
+		"tag_id":     29,
+		"feature_id": 29,
+	}
+
+	bannerETag = map[string]interface{}{
+		"tag_ids":    []int{30},
+		"feature_id": 29,
+		"content": map[string]string{
+			"title": "ETag banner",
+			"text":  "ETag text",
+			"url":   "https://etag.url",
+		},
+		"is_active": true,
+	}
+
+	bannerETagUpdate = map[string]interface{}{
+		"content": map[string]string{
+			"title": "Updated ETag banner",
+		},
+	}
+
+	bannerIdempotent = map[string]interface{}{
+		"tag_ids":    []int{31},
+		"feature_id": 29,
+		"content": map[string]string{
+			"title": "Idempotent banner",
+			"text":  "Idempotent text",
+			"url":   "https://idempotent.url",
+		},
+		"is_active": true,
+	}
+
+	bannerIdempotentChanged = map[string]interface{}{
+		"tag_ids":    []int{31},
+		"feature_id": 29,
+		"content": map[string]string{
+			"title": "Changed banner",
+			"text":  "Changed text",
+			"url":   "https://changed.url",
+		},
+		"is_active": true,
+	}
+
+	bannerClone = map[string]interface{}{
+		"tag_ids":    []int{32},
+		"feature_id": 31,
+		"content": map[string]string{
+			"title": "Cloned banner",
+			"text":  "Cloned text",
+			"url":   "https://cloned.url",
+		},
+		"is_active": true,
+	}
+
+	cloneOverrides = map[string]interface{}{
+		"tag_ids": []int{33},
+	}
+
+	savedTemplate = map[string]interface{}{
+		"name":       "Promo template",
+		"feature_id": 31,
+		"content": map[string]string{
+			"title": "Promo banner",
+			"text":  "Promo text",
+			"url":   "https://promo.url",
+		},
+		"is_active": true,
+	}
+
+	savedTemplateUpdate = map[string]interface{}{
+		"description": "Spring promo",
+	}
+
+	templateInstance = map[string]interface{}{
+		"tag_ids":  []int{33},
+		"priority": 1,
+	}
+
+	bannerImportFirst = map[string]interface{}{
+		"tag_ids":    []int{34},
+		"feature_id": 31,
+		"content": map[string]string{
+			"title": "First imported banner",
+			"text":  "First imported text",
+			"url":   "https://first-imported.url",
+		},
+		"is_active": true,
+		"status":    "published",
+	}
+
+	bannerImportSecond = map[string]interface{}{
+		"tag_ids":    []int{35},
+		"feature_id": 31,
+		"content": map[string]string{
+			"title": "Second imported banner",
+			"text":  "Second imported text",
+			"url":   "https://second-imported.url",
+		},
+		"is_active": true,
+		"status":    "draft",
+	}
+
+	bannerImportUnknownFeature = map[string]interface{}{
+		"tag_ids":    []int{35},
+		"feature_id": 999,
+		"content": map[string]string{
+			"title": "Unknown feature banner",
+		},
+		"is_active": true,
+	}
+
+	filteredBanners = []map[string]interface{}{
+		{
+			"tag_ids":    []int{36},
+			"feature_id": 32,
+			"content":    map[string]string{"title": "Alpha sale"},
+			"is_active":  true,
+			"priority":   1,
+		},
+		{
+			"tag_ids":    []int{36},
+			"feature_id": 32,
+			"content":    map[string]string{"title": "Beta sale"},
+			"is_active":  false,
+			"priority":   2,
+		},
+		{
+			"tag_ids":    []int{36},
+			"feature_id": 32,
+			"content":    map[string]string{"title": "Gamma"},
+			"localized_content": map[string]interface{}{
+				"de": map[string]string{"title": "Gamma Angebot"},
+			},
+			"is_active": true,
+			"priority":  3,
+		},
+	}
+
+	softDeleteBannerSearch = map[string]interface{}{
+		"tag_id":     28,
+		"feature_id": 28,
+	}
+
+	bannerRevision = map[string]interface{}{
+		"tag_ids":    []int{10},
+		"feature_id": 10,
+		"content": map[string]string{
+			"title": "Revision banner",
+			"text":  "Revision text",
+			"url":   "https://revision.url",
+		},
+		"is_active": true,
+	}
+
+	bannerRevisionUpdate = map[string]interface{}{
+		"content": map[string]string{
+			"title": "Updated revision banner",
+		},
+		"is_active": true,
+	}
+
+	revisionBannerSearch = map[string]interface{}{
+		"tag_id":     10,
+		"feature_id": 10,
+	}
+
+	lastRevisionBannerSearch = map[string]interface{}{
+		"tag_id":            10,
+		"feature_id":        10,
+		"use_last_revision": true,
+	}
+
+	bannerCache = map[string]interface{}{
+		"tag_ids":    []int{20},
+		"feature_id": 20,
+		"content": map[string]string{
+			"title": "Cache banner",
+			"text":  "Cache text",
+			"url":   "https://cache.url",
+		},
+		"is_active": true,
+	}
+
+	bannerCacheUpdate = map[string]interface{}{
+		"content": map[string]string{
+			"title": "Updated cache banner",
+		},
+		"is_active": true,
+	}
+
+	cacheBannerSearch = map[string]interface{}{
+		"tag_id":     20,
+		"feature_id": 20,
+	}
+
+	bannerBulkDelete1 = map[string]interface{}{
+		"tag_ids":    []int{30},
+		"feature_id": 30,
+		"content": map[string]string{
+			"title": "Bulk delete banner1",
+			"text":  "Bulk delete text1",
+			"url":   "https://bulk1.url",
+		},
+		"is_active": true,
+	}
+
+	bannerBulkDelete2 = map[string]interface{}{
+		"tag_ids":    []int{31},
+		"feature_id": 30,
+		"content": map[string]string{
+			"title": "Bulk delete banner2",
+			"text":  "Bulk delete text2",
+			"url":   "https://bulk2.url",
+		},
+		"is_active": true,
+	}
+
+	bulkDeleteBannerSearch = map[string]interface{}{
+		"tag_id":     30,
+		"feature_id": 30,
+	}
+
 	inactiveBannerSearch = map[string]interface{}{
 		"tag_id":     2,
 		"feature_id": 2,
 	}
 
 	activeBannerSearch = map[string]interface{}{
-		"tag_id":     0,
+		"tag_id":     3,
 		"feature_id": 3,
 	}
 )
+
+func tagTargetedBanner(tagId int) map[string]interface{} {
+	return map[string]interface{}{
+		"tag_ids":    []int{25},
+		"feature_id": 25,
+		"content": map[string]string{
+			"title": "Tag targeted banner",
+			"text":  "Tag targeted text",
+			"url":   "https://targeted.url",
+		},
+		"targeting": map[string]interface{}{
+			"any": []interface{}{map[string]interface{}{"not": map[string]interface{}{"tag": tagId}}},
+		},
+		"is_active": true,
+		"priority":  1,
+	}
+}
+
+func softDeletedTagBanner(tagId int) map[string]interface{} {
+	return map[string]interface{}{
+		"tag_ids":    []int{tagId},
+		"feature_id": 28,
+		"content": map[string]string{
+			"title": "Orphaned banner",
+			"text":  "Orphaned text",
+			"url":   "https://orphaned.url",
+		},
+		"is_active": true,
+	}
+}
+
+func scheduledBanner(tagId int, startsAt, endsAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"tag_ids":    []int{tagId},
+		"feature_id": 12,
+		"content": map[string]string{
+			"title": "Scheduled banner",
+			"text":  "Scheduled text",
+			"url":   "https://scheduled.url",
+		},
+		"is_active": true,
+		"starts_at": startsAt,
+		"ends_at":   endsAt,
+	}
+}
@@ -7,3 +7,10 @@ type User struct {
 	Password string `json:"password" binding:"required"`
 	Role     string `json:"role" binding:"required"`
 }
+
+// Claims are the user attributes carried by the access token.
+type Claims struct {
+	UserId   int    `json:"user_id"`
+	Role     string `json:"role"`
+	Nickname string `json:"nickname"`
+}
@@ -0,0 +1,20 @@
+package banner
+
+// Workflow statuses. Only published banners are served to users.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusApproved  = "approved"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Workflow actions move a banner between statuses and are recorded in the
+// audit log under the same name.
+const (
+	ActionSubmit  = "submit"
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionPublish = "publish"
+	ActionArchive = "archive"
+)